@@ -0,0 +1,12 @@
+package admin
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+func UseSubroute(group *echo.Group, handler *handlers.AdminHandler) {
+	group.GET("/cycles", handler.Cycles)
+	group.GET("/snapshots", handler.Snapshots)
+	group.GET("/snapshots/:id", handler.Snapshot)
+}
@@ -0,0 +1,13 @@
+package blends
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+func UseSubroute(group *echo.Group, handler *handlers.BlendHandler) {
+	group.GET("", handler.Index)
+	group.POST("", handler.Create)
+	group.GET("/:token", handler.Page)
+	group.POST("/:token/revoke", handler.Revoke)
+}
@@ -0,0 +1,12 @@
+package chains
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+func UseSubroute(group *echo.Group, handler *handlers.ChainHandler) {
+	group.GET("", handler.Page)
+	group.GET("/list", handler.List)
+	group.GET("/:id", handler.Show)
+}
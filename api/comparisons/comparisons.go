@@ -0,0 +1,11 @@
+package comparisons
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+func UseSubroute(group *echo.Group, handler *handlers.ComparisonHandler) {
+	group.GET("/next", handler.Next)
+	group.POST("", handler.Submit)
+}
@@ -0,0 +1,20 @@
+package groups
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+func UseSubroute(group *echo.Group, handler *handlers.GroupHandler) {
+	group.GET("", handler.List)
+	group.POST("", handler.Create)
+	group.GET("/:id", handler.Show)
+	group.POST("/:id/invites", handler.Invite)
+	group.POST("/:id/join", handler.Join)
+	group.POST("/:id/leave", handler.Leave)
+	group.POST("/:id/status", handler.Status)
+	group.POST("/:id/radius", handler.Radius)
+	group.GET("/:id/ranking", handler.Ranking)
+	group.POST("/:id/availability", handler.Availability)
+	group.GET("/:id/times", handler.Times)
+}
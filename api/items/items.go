@@ -0,0 +1,20 @@
+package items
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/Jerell/tasteranker/internal/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// UseSubroute registers the item routes. Anyone logged in can add an item,
+// but only admins can rename, archive or restore one.
+func UseSubroute(group *echo.Group, handler *handlers.ItemHandler) {
+	group.GET("", handler.List)
+	group.GET("/types", handler.Types)
+	group.GET("/search", handler.Search)
+	group.GET("/:id", handler.Show)
+	group.POST("", handler.Create)
+	group.POST("/:id", handler.Update, auth.RequireAdmin)
+	group.POST("/:id/archive", handler.Archive, auth.RequireAdmin)
+	group.POST("/:id/restore", handler.Restore, auth.RequireAdmin)
+}
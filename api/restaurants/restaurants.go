@@ -0,0 +1,10 @@
+package restaurants
+
+import (
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+func UseSubroute(group *echo.Group, handler *handlers.RestaurantHandler) {
+	group.GET("/nearby", handler.Nearby)
+}
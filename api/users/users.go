@@ -9,11 +9,13 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func UseSubroute(group *echo.Group, store *db.UserStore) {
+func UseSubroute(group *echo.Group, store *db.UserStore, taste *handlers.TasteHandler) {
     handler := handlers.NewUserHandler(store)
 
     group.GET("list", handler.List)
     group.POST("", handler.Create)
+    group.GET(":id/recommendations", taste.Recommendations)
+    group.GET(":id/similarity/:other", taste.Similarity)
     
     group.GET("*", func(c echo.Context) error {
         key := c.Param("*")
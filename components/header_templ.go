@@ -13,6 +13,10 @@ import (
 )
 
 var mainMenu = []Page{
+	{label: "leaderboard", href: "/leaderboard"},
+	{label: "chains", href: "/chains"},
+	{label: "groups", href: "/groups"},
+	{label: "blend", href: "/blend"},
 	{label: "about", href: "/about"},
 }
 
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jerell/tasteranker/internal/analysis"
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/labstack/echo/v4"
+)
+
+type AdminHandler struct {
+	analysis *analysis.Service
+	ratings  *rating.Store
+}
+
+func NewAdminHandler(analysis *analysis.Service, ratings *rating.Store) *AdminHandler {
+	return &AdminHandler{analysis: analysis, ratings: ratings}
+}
+
+// Cycles reports where matchup results contradict each other. The limit
+// query parameter caps how many violated triples are listed.
+func (h *AdminHandler) Cycles(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	report, err := h.analysis.Cycles(c.Request().Context(), limit)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// Snapshots lists the rating snapshots saved by the snapshot command, newest
+// first. The limit query parameter caps how many are listed.
+func (h *AdminHandler) Snapshots(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	snapshots, err := h.ratings.Snapshots(c.Request().Context(), limit)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(http.StatusOK, snapshots)
+}
+
+// Snapshot returns one rating snapshot with its ratings.
+func (h *AdminHandler) Snapshot(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Snapshot not found",
+		})
+	}
+
+	snap, err := h.ratings.Snapshot(c.Request().Context(), id)
+	if err == rating.ErrSnapshotNotFound {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Snapshot not found",
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(http.StatusOK, snap)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/internal/blend"
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/taste"
+	"github.com/labstack/echo/v4"
+)
+
+// blendLength is how many places a blend lists.
+const blendLength = 10
+
+type BlendHandler struct {
+	blends *blend.Service
+	taste  *taste.Service
+	users  *db.UserStore
+}
+
+func NewBlendHandler(blends *blend.Service, taste *taste.Service, users *db.UserStore) *BlendHandler {
+	return &BlendHandler{blends: blends, taste: taste, users: users}
+}
+
+// Index lets the logged in user create a blend link.
+func (h *BlendHandler) Index(c echo.Context) error {
+	_, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return components.Render(c, http.StatusOK, components.Main(components.BlendLogin()))
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	return components.Render(c, http.StatusOK, components.Main(components.BlendStart(csrfToken(c))))
+}
+
+// Create issues a new blend link from the logged in user.
+func (h *BlendHandler) Create(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return h.respondError(c, http.StatusUnauthorized, "Log in to create a blend link")
+	}
+	if err != nil {
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+
+	token, link, err := h.blends.Create(user.ID)
+	if err != nil {
+		c.Logger().Error(err)
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+	url := c.Scheme() + "://" + c.Request().Host + "/blend/" + token
+	if wantsHTML(c) {
+		return components.Render(c, http.StatusCreated, components.BlendLink(url, token, link.ExpiresAt, csrfToken(c)))
+	}
+	return c.JSON(http.StatusCreated, map[string]any{
+		"url":        url,
+		"token":      token,
+		"id":         link.ID,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// Page shows the logged in user the places they and the link's creator
+// would both enjoy.
+func (h *BlendHandler) Page(c echo.Context) error {
+	link, err := h.blends.Open(c.Request().Context(), c.Param("token"))
+	if err != nil {
+		status, message := blendError(err)
+		if status == http.StatusInternalServerError {
+			c.Logger().Error(err)
+		}
+		return components.Render(c, status, components.Main(components.BlendUnavailable(message)))
+	}
+
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return components.Render(c, http.StatusOK, components.Main(components.BlendLogin()))
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	if user.ID == link.CreatorID {
+		return components.Render(
+			c, http.StatusOK,
+			components.Main(components.BlendOwn(c.Param("token"), csrfToken(c))),
+		)
+	}
+
+	ctx := c.Request().Context()
+	creator, err := h.users.GetByID(ctx, link.CreatorID)
+	if err == db.ErrUserNotFound {
+		return components.Render(
+			c, http.StatusNotFound,
+			components.Main(components.BlendUnavailable("The person who sent this link has left.")),
+		)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+
+	places, err := h.taste.Blend(ctx, user.ID, creator.ID, blendLength)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	return components.Render(c, http.StatusOK, components.Main(components.Blend(creator.Name, places)))
+}
+
+// Revoke stops a link the logged in user created from opening.
+func (h *BlendHandler) Revoke(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return h.respondError(c, http.StatusUnauthorized, "Log in to revoke a blend link")
+	}
+	if err != nil {
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+
+	err = h.blends.Revoke(c.Request().Context(), user.ID, c.Param("token"))
+	switch err {
+	case nil:
+		if wantsHTML(c) {
+			return components.Render(c, http.StatusOK, components.BlendRevoked())
+		}
+		return c.NoContent(http.StatusNoContent)
+	case blend.ErrInvalidLink:
+		return h.respondError(c, http.StatusNotFound, "Blend link not found")
+	case blend.ErrNotCreator:
+		return h.respondError(c, http.StatusForbidden, "Only the creator can revoke a blend link")
+	default:
+		c.Logger().Error(err)
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+func (h *BlendHandler) respondError(c echo.Context, status int, message string) error {
+	if wantsHTML(c) {
+		return c.String(status, message)
+	}
+	return c.JSON(status, map[string]string{
+		"error": message,
+	})
+}
+
+func blendError(err error) (int, string) {
+	switch err {
+	case blend.ErrInvalidLink:
+		return http.StatusNotFound, "This blend link is not valid."
+	case blend.ErrLinkExpired:
+		return http.StatusGone, "This blend link has expired. Ask for a new one."
+	case blend.ErrLinkRevoked:
+		return http.StatusGone, "This blend link has been turned off."
+	default:
+		return http.StatusInternalServerError, "Something went wrong opening this blend link."
+	}
+}
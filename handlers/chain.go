@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/internal/chains"
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+type ChainHandler struct {
+	chains *chains.Service
+}
+
+func NewChainHandler(chains *chains.Service) *ChainHandler {
+	return &ChainHandler{chains: chains}
+}
+
+// Page shows the chain leaderboard. The method query parameter picks how
+// chains are rated from their branches.
+func (h *ChainHandler) Page(c echo.Context) error {
+	method, err := chains.ParseMethod(c.QueryParam("method"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	board, err := h.chains.Board(c.Request().Context(), method)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	return components.Render(
+		c, http.StatusOK,
+		components.Main(components.ChainLeaderboard(board)),
+	)
+}
+
+func (h *ChainHandler) List(c echo.Context) error {
+	method, err := chains.ParseMethod(c.QueryParam("method"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	board, err := h.chains.Board(c.Request().Context(), method)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(http.StatusOK, board)
+}
+
+// Show returns a chain with each of its branches' ratings, as a page to
+// browsers and as JSON to everything else.
+func (h *ChainHandler) Show(c echo.Context) error {
+	chainID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.respondError(c, http.StatusNotFound, "Chain not found")
+	}
+	method, err := chains.ParseMethod(c.QueryParam("method"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err.Error())
+	}
+
+	detail, err := h.chains.Chain(c.Request().Context(), chainID, method)
+	if err == db.ErrChainNotFound {
+		return h.respondError(c, http.StatusNotFound, "Chain not found")
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+
+	if wantsHTML(c) {
+		return components.Render(
+			c, http.StatusOK,
+			components.Main(components.ChainPage(detail)),
+		)
+	}
+	return c.JSON(http.StatusOK, detail)
+}
+
+func (h *ChainHandler) respondError(c echo.Context, status int, message string) error {
+	if wantsHTML(c) {
+		return c.String(status, message)
+	}
+	return c.JSON(status, map[string]string{
+		"error": message,
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/pairing"
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// feedLength is how many pairs are queued up in the comparison feed.
+const feedLength = 5
+
+type ComparisonHandler struct {
+	pairs    *pairing.Service
+	matchups *db.MatchupStore
+	users    *db.UserStore
+}
+
+func NewComparisonHandler(pairs *pairing.Service, matchups *db.MatchupStore, users *db.UserStore) *ComparisonHandler {
+	return &ComparisonHandler{pairs: pairs, matchups: matchups, users: users}
+}
+
+// Next renders the comparison feed on its own for htmx to swap in.
+func (h *ComparisonHandler) Next(c echo.Context) error {
+	comparison, err := h.comparison(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	return components.Render(c, http.StatusOK, comparison)
+}
+
+// Submit records the user's verdict on the pair at the front of the feed and
+// responds with the feed moved on to the next pair.
+func (h *ComparisonHandler) Submit(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return components.Render(c, http.StatusUnauthorized, components.ComparisonLogin())
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+
+	item1, err1 := strconv.Atoi(c.FormValue("item1_id"))
+	item2, err2 := strconv.Atoi(c.FormValue("item2_id"))
+	if err1 != nil || err2 != nil {
+		return c.String(http.StatusBadRequest, "Invalid matchup")
+	}
+
+	// The draw and skip buttons send an outcome; the item buttons send the
+	// winner instead.
+	outcome := db.Outcome(c.FormValue("outcome"))
+	var winner int
+	if outcome == "" {
+		outcome = db.OutcomeWin
+		winner, err = strconv.Atoi(c.FormValue("winner_id"))
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid matchup")
+		}
+	}
+
+	_, _, err = h.matchups.Record(c.Request().Context(), user.ID, item1, item2, outcome, winner, nil)
+	switch err {
+	case nil:
+	case db.ErrInvalidMatchup:
+		return c.String(http.StatusBadRequest, "Invalid matchup")
+	default:
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+
+	return h.Next(c)
+}
+
+func (h *ComparisonHandler) comparison(c echo.Context) (templ.Component, error) {
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return components.ComparisonLogin(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := h.pairs.Next(c.Request().Context(), user.ID, feedLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return components.ComparisonDone(), nil
+	}
+
+	csrf, _ := c.Get(middleware.DefaultCSRFConfig.ContextKey).(string)
+	return components.Comparison(pairs, csrf), nil
+}
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/hours"
+	"github.com/Jerell/tasteranker/internal/planning"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// GroupHandler serves both htmx and JSON clients. Requests from htmx, or
+// from a browser asking for HTML, get components back and everything else
+// gets JSON.
+type GroupHandler struct {
+	groups  *db.GroupStore
+	planner *planning.Service
+	users   *db.UserStore
+}
+
+func NewGroupHandler(groups *db.GroupStore, planner *planning.Service, users *db.UserStore) *GroupHandler {
+	return &GroupHandler{groups: groups, planner: planner, users: users}
+}
+
+// List shows the user's groups and the invites waiting for them.
+func (h *GroupHandler) List(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	ctx := c.Request().Context()
+	groups, err := h.groups.ListForUser(ctx, user.ID)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	invites, err := h.groups.InvitesFor(ctx, user.Email)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if wantsHTML(c) {
+		return components.Render(
+			c, http.StatusOK,
+			components.Main(components.Groups(groups, invites, csrfToken(c))),
+		)
+	}
+	if groups == nil {
+		groups = []db.Group{}
+	}
+	if invites == nil {
+		invites = []db.GroupInvite{}
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"groups":  groups,
+		"invites": invites,
+	})
+}
+
+// Create starts a new group from the name and optional event_time fields.
+func (h *GroupHandler) Create(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	var input struct {
+		Name      string `json:"name" form:"name"`
+		EventTime string `json:"event_time" form:"event_time"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+	}
+	eventTime, err := parseEventTime(input.EventTime)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid event time")
+	}
+
+	group, err := h.groups.Create(c.Request().Context(), user.ID, input.Name, eventTime)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if wantsHTML(c) {
+		c.Response().Header().Set("HX-Redirect", "/groups/"+strconv.Itoa(group.ID))
+		return c.NoContent(http.StatusCreated)
+	}
+	return c.JSON(http.StatusCreated, group)
+}
+
+// Show returns a group to its members and to anyone invited to it.
+func (h *GroupHandler) Show(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if !group.IsMember(user.ID) && !invited(group, user.Email) {
+		return h.fail(c, db.ErrGroupNotFound)
+	}
+
+	if wantsHTML(c) && !isHTMX(c) {
+		return components.Render(
+			c, http.StatusOK,
+			components.Main(components.GroupPage(group, user.ID, user.Email, csrfToken(c))),
+		)
+	}
+	return h.respond(c, http.StatusOK, group, user)
+}
+
+// Invite invites the email field's owner to the group.
+func (h *GroupHandler) Invite(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	var input struct {
+		Email string `json:"email" form:"email"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	invite, err := h.groups.Invite(ctx, group.ID, user.ID, input.Email)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if wantsHTML(c) {
+		group, err = h.groups.GetByID(ctx, group.ID)
+		if err != nil {
+			return h.fail(c, err)
+		}
+		return h.respond(c, http.StatusCreated, group, user)
+	}
+	return c.JSON(http.StatusCreated, invite)
+}
+
+// Join accepts the user's invite to the group.
+func (h *GroupHandler) Join(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.fail(c, db.ErrGroupNotFound)
+	}
+
+	group, err := h.groups.Join(c.Request().Context(), groupID, user.ID)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if isHTMX(c) {
+		c.Response().Header().Set("HX-Redirect", "/groups/"+strconv.Itoa(group.ID))
+		return c.NoContent(http.StatusOK)
+	}
+	return h.respond(c, http.StatusOK, group, user)
+}
+
+// Leave takes the user out of the group.
+func (h *GroupHandler) Leave(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.fail(c, db.ErrGroupNotFound)
+	}
+
+	if err := h.groups.Leave(c.Request().Context(), groupID, user.ID); err != nil {
+		return h.fail(c, err)
+	}
+
+	if isHTMX(c) {
+		c.Response().Header().Set("HX-Redirect", "/groups")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Status moves the group on to the status field's value.
+func (h *GroupHandler) Status(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	var input struct {
+		Status db.GroupStatus `json:"status" form:"status"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	group, err = h.groups.SetStatus(c.Request().Context(), group.ID, user.ID, input.Status)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respond(c, http.StatusOK, group, user)
+}
+
+// Radius sets how far the user will travel for the group, from the
+// search_radius_meters field. Zero or empty clears it.
+func (h *GroupHandler) Radius(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	var input struct {
+		Meters string `json:"search_radius_meters" form:"search_radius_meters"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+	}
+	meters := 0
+	if input.Meters != "" {
+		if meters, err = strconv.Atoi(input.Meters); err != nil {
+			return h.respondError(c, http.StatusBadRequest, "Invalid search radius")
+		}
+	}
+
+	ctx := c.Request().Context()
+	if err := h.groups.SetSearchRadius(ctx, group.ID, user.ID, meters); err != nil {
+		return h.fail(c, err)
+	}
+	group, err = h.groups.GetByID(ctx, group.ID)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respond(c, http.StatusOK, group, user)
+}
+
+// Ranking ranks the places every member can reach. The aggregation query
+// parameter picks how members' ratings are combined and limit caps how many
+// places are returned.
+func (h *GroupHandler) Ranking(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if !group.IsMember(user.ID) {
+		return h.fail(c, db.ErrNotMember)
+	}
+
+	agg, err := planning.ParseAggregation(c.QueryParam("aggregation"))
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, err.Error())
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	ranking, err := h.planner.Rank(c.Request().Context(), group.ID, agg, limit)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if wantsHTML(c) {
+		return components.Render(c, http.StatusOK, components.GroupRanking(ranking))
+	}
+	return c.JSON(http.StatusOK, ranking)
+}
+
+// Availability sets when the user is free. A JSON body of windows replaces
+// them all, while a form adds the window from its start and end fields, or
+// clears them all when clear is set.
+func (h *GroupHandler) Availability(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	var availability db.Availability
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		if err := c.Bind(&availability); err != nil {
+			return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+		}
+	} else {
+		var input struct {
+			Start string `form:"start"`
+			End   string `form:"end"`
+			Clear bool   `form:"clear"`
+		}
+		if err := c.Bind(&input); err != nil {
+			return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+		}
+		if !input.Clear {
+			start, err := parseLocalTime(input.Start)
+			if err != nil {
+				return h.fail(c, db.ErrInvalidAvailability)
+			}
+			end, err := parseLocalTime(input.End)
+			if err != nil {
+				return h.fail(c, db.ErrInvalidAvailability)
+			}
+			for _, m := range group.Members {
+				if m.UserID == user.ID {
+					availability = m.Availability
+				}
+			}
+			availability.Windows = append(availability.Windows, db.AvailabilityWindow{Start: start, End: end})
+		}
+	}
+
+	ctx := c.Request().Context()
+	availability, err = h.groups.SetAvailability(ctx, group.ID, user.ID, availability)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if wantsHTML(c) {
+		group, err = h.groups.GetByID(ctx, group.ID)
+		if err != nil {
+			return h.fail(c, err)
+		}
+		return h.respond(c, http.StatusOK, group, user)
+	}
+	return c.JSON(http.StatusOK, availability)
+}
+
+// Times finds the best times for the group to meet. The duration query
+// parameter, such as 90m or 2h, is how long they need and limit caps how
+// many slots are returned.
+func (h *GroupHandler) Times(c echo.Context) error {
+	user, group, err := h.group(c)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if !group.IsMember(user.ID) {
+		return h.fail(c, db.ErrNotMember)
+	}
+
+	var opts planning.TimeOptions
+	if d := c.QueryParam("duration"); d != "" {
+		if opts.MinDuration, err = time.ParseDuration(d); err != nil || opts.MinDuration <= 0 {
+			return h.respondError(c, http.StatusBadRequest, "Invalid duration")
+		}
+	}
+	opts.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+
+	times, err := h.planner.BestTimes(c.Request().Context(), group.ID, opts)
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	if wantsHTML(c) {
+		return components.Render(c, http.StatusOK, components.GroupTimes(times))
+	}
+	return c.JSON(http.StatusOK, times)
+}
+
+// group returns the logged in user and the group in the path. Only members
+// can change a group.
+func (h *GroupHandler) group(c echo.Context) (*db.User, *db.Group, error) {
+	user, err := currentUser(c, h.users)
+	if err != nil {
+		return nil, nil, err
+	}
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, nil, db.ErrGroupNotFound
+	}
+
+	group, err := h.groups.GetByID(c.Request().Context(), groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !group.IsMember(user.ID) && c.Request().Method != http.MethodGet {
+		return nil, nil, db.ErrNotMember
+	}
+	return user, group, nil
+}
+
+func (h *GroupHandler) respond(c echo.Context, status int, group *db.Group, user *db.User) error {
+	if wantsHTML(c) {
+		return components.Render(c, status, components.GroupDetail(group, user.ID, user.Email, csrfToken(c)))
+	}
+	return c.JSON(status, group)
+}
+
+func (h *GroupHandler) fail(c echo.Context, err error) error {
+	switch err {
+	case errNotLoggedIn:
+		return h.respondError(c, http.StatusUnauthorized, "Log in to plan with a group")
+	case db.ErrGroupNotFound:
+		return h.respondError(c, http.StatusNotFound, "Group not found")
+	case db.ErrInvalidGroup:
+		return h.respondError(c, http.StatusBadRequest, "Invalid group data")
+	case db.ErrNotInvited:
+		return h.respondError(c, http.StatusForbidden, "You have not been invited to this group")
+	case db.ErrNotMember:
+		return h.respondError(c, http.StatusForbidden, "You are not a member of this group")
+	case db.ErrNotGroupCreator:
+		return h.respondError(c, http.StatusForbidden, "Only the group's creator can do that")
+	case db.ErrCreatorCannotLeave:
+		return h.respondError(c, http.StatusConflict, "The group's creator can't leave it")
+	case db.ErrAlreadyMember:
+		return h.respondError(c, http.StatusConflict, "Already a member of this group")
+	case db.ErrInvalidTransition:
+		return h.respondError(c, http.StatusConflict, "The group can't move to that status")
+	case db.ErrInvalidAvailability:
+		return h.respondError(c, http.StatusBadRequest, "Invalid availability")
+	default:
+		c.Logger().Error(err)
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+func (h *GroupHandler) respondError(c echo.Context, status int, message string) error {
+	if wantsHTML(c) {
+		return c.String(status, message)
+	}
+	return c.JSON(status, map[string]string{
+		"error": message,
+	})
+}
+
+func invited(group *db.Group, email string) bool {
+	for _, inv := range group.Invites {
+		if strings.EqualFold(inv.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEventTime accepts the same values as parseLocalTime, so an event time
+// lines up with the availability and opening hours it is compared with. An
+// empty value means the group has not picked a time yet.
+func parseEventTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := parseLocalTime(value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parseLocalTime accepts RFC 3339 or the value of a datetime-local input,
+// which is read as the time in hours.DefaultTimeZone.
+func parseLocalTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	loc, err := time.LoadLocation(hours.DefaultTimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("2006-01-02T15:04", value, loc)
+}
+
+func isHTMX(c echo.Context) bool {
+	return c.Request().Header.Get("HX-Request") == "true"
+}
+
+func wantsHTML(c echo.Context) bool {
+	return isHTMX(c) || strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMETextHTML)
+}
+
+func csrfToken(c echo.Context) string {
+	csrf, _ := c.Get(middleware.DefaultCSRFConfig.ContextKey).(string)
+	return csrf
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/leaderboard"
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// homeLeaderboardSize is how many items the home page leaderboard shows.
+const homeLeaderboardSize = 5
+
+type HomeHandler struct {
+	comparisons *ComparisonHandler
+	leaderboard *leaderboard.Service
+	users       *db.UserStore
+}
+
+func NewHomeHandler(comparisons *ComparisonHandler, leaderboard *leaderboard.Service, users *db.UserStore) *HomeHandler {
+	return &HomeHandler{comparisons: comparisons, leaderboard: leaderboard, users: users}
+}
+
+func (h *HomeHandler) Home(c echo.Context) error {
+	comparison, err := h.comparisons.comparison(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+
+	ranking, err := h.ranking(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+
+	return components.Render(
+		c, http.StatusOK,
+		components.Main(components.Home(comparison, ranking)),
+	)
+}
+
+// ranking shows logged in users their personal top five once they have one,
+// and everyone else the global top five.
+func (h *HomeHandler) ranking(c echo.Context) (templ.Component, error) {
+	ctx := c.Request().Context()
+
+	user, err := currentUser(c, h.users)
+	if err != nil && err != errNotLoggedIn {
+		return nil, err
+	}
+	if user != nil {
+		entries, err := h.leaderboard.Personal(ctx, user.ID, homeLeaderboardSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			return components.YourTopFive(entries), nil
+		}
+	}
+
+	board, err := h.leaderboard.Board(ctx, leaderboard.Options{PerPage: homeLeaderboardSize})
+	if err != nil {
+		return nil, err
+	}
+	return components.TopFive(board.Entries), nil
+}
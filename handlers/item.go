@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// maxItemLimit is the most items one list or search returns.
+const maxItemLimit = 100
+
+type ItemHandler struct {
+	items *db.ItemStore
+	users *db.UserStore
+}
+
+func NewItemHandler(items *db.ItemStore, users *db.UserStore) *ItemHandler {
+	return &ItemHandler{items: items, users: users}
+}
+
+// Types lists the kinds of item that can be ranked.
+func (h *ItemHandler) Types(c echo.Context) error {
+	types, err := h.items.Types(c.Request().Context())
+	if err != nil {
+		return h.fail(c, err)
+	}
+	if types == nil {
+		types = []db.ItemType{}
+	}
+	return c.JSON(http.StatusOK, types)
+}
+
+// List returns the items of the type query parameter, restaurant by default,
+// paged with limit and offset.
+func (h *ItemHandler) List(c echo.Context) error {
+	typeName := c.QueryParam("type")
+	if typeName == "" {
+		typeName = "restaurant"
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	items, err := h.items.ListByType(c.Request().Context(), typeName, min(limit, maxItemLimit), offset)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respondItems(c, items)
+}
+
+// Search finds items whose names contain the q query parameter, optionally
+// of one type.
+func (h *ItemHandler) Search(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	items, err := h.items.Search(
+		c.Request().Context(),
+		c.QueryParam("q"),
+		c.QueryParam("type"),
+		min(limit, maxItemLimit),
+	)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return h.respondItems(c, items)
+}
+
+func (h *ItemHandler) Show(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.fail(c, db.ErrItemNotFound)
+	}
+	item, err := h.items.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+// Create adds an item on behalf of the logged in user.
+func (h *ItemHandler) Create(c echo.Context) error {
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return h.respondError(c, http.StatusUnauthorized, "Log in to add an item")
+	}
+	if err != nil {
+		return h.fail(c, err)
+	}
+
+	var input struct {
+		Type string `json:"type" form:"type"`
+		Name string `json:"name" form:"name"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	item, err := h.items.Create(c.Request().Context(), input.Type, input.Name, user.ID)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusCreated, item)
+}
+
+// Update renames an item.
+func (h *ItemHandler) Update(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.fail(c, db.ErrItemNotFound)
+	}
+
+	var input struct {
+		Name string `json:"name" form:"name"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	item, err := h.items.Update(c.Request().Context(), id, input.Name)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+// Archive stops an item being offered, keeping its matchups and ratings.
+func (h *ItemHandler) Archive(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.fail(c, db.ErrItemNotFound)
+	}
+	if err := h.items.Archive(c.Request().Context(), id); err != nil {
+		return h.fail(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Restore brings back an archived item.
+func (h *ItemHandler) Restore(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.fail(c, db.ErrItemNotFound)
+	}
+	item, err := h.items.Restore(c.Request().Context(), id)
+	if err != nil {
+		return h.fail(c, err)
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+func (h *ItemHandler) respondItems(c echo.Context, items []db.Item) error {
+	if items == nil {
+		items = []db.Item{}
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+func (h *ItemHandler) fail(c echo.Context, err error) error {
+	switch err {
+	case db.ErrItemNotFound:
+		return h.respondError(c, http.StatusNotFound, "Item not found")
+	case db.ErrItemTypeNotFound:
+		return h.respondError(c, http.StatusBadRequest, "Unknown item type")
+	case db.ErrInvalidItemData:
+		return h.respondError(c, http.StatusBadRequest, "Invalid item data")
+	default:
+		c.Logger().Error(err)
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+func (h *ItemHandler) respondError(c echo.Context, status int, message string) error {
+	return c.JSON(status, map[string]string{
+		"error": message,
+	})
+}
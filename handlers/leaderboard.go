@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/internal/leaderboard"
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/labstack/echo/v4"
+)
+
+type LeaderboardHandler struct {
+	service *leaderboard.Service
+	ratings *rating.Store
+}
+
+func NewLeaderboardHandler(service *leaderboard.Service, ratings *rating.Store) *LeaderboardHandler {
+	return &LeaderboardHandler{service: service, ratings: ratings}
+}
+
+func (h *LeaderboardHandler) Page(c echo.Context) error {
+	opts, err := leaderboardOptions(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	board, err := h.service.Board(c.Request().Context(), opts)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	return components.Render(
+		c, http.StatusOK,
+		components.Main(components.Leaderboard(board)),
+	)
+}
+
+func (h *LeaderboardHandler) List(c echo.Context) error {
+	opts, err := leaderboardOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	board, err := h.service.Board(c.Request().Context(), opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(http.StatusOK, board)
+}
+
+// leaderboardOptions reads paging, the segment and the half-life from the
+// query string. Missing or invalid paging falls back to the service defaults,
+// for example ?page=2&segment=nationality:italian,diet:vegetarian&half_life=90d.
+func leaderboardOptions(c echo.Context) (leaderboard.Options, error) {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	perPage, _ := strconv.Atoi(c.QueryParam("per_page"))
+
+	segment, err := rating.ParseSegment(c.QueryParam("segment"))
+	if err != nil {
+		return leaderboard.Options{}, err
+	}
+	halfLife, err := rating.ParseHalfLife(c.QueryParam("half_life"))
+	if err != nil {
+		return leaderboard.Options{}, err
+	}
+
+	return leaderboard.Options{Page: page, PerPage: perPage, Segment: segment, HalfLife: halfLife}, nil
+}
+
+// History returns an item's recorded ratings between the optional from and
+// to query parameters, or the single rating it had at the at parameter.
+func (h *LeaderboardHandler) History(c echo.Context) error {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid item id",
+		})
+	}
+
+	from, err1 := parseTimeParam(c.QueryParam("from"))
+	to, err2 := parseTimeParam(c.QueryParam("to"))
+	at, err3 := parseTimeParam(c.QueryParam("at"))
+	if err1 != nil || err2 != nil || err3 != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Times must be dates or RFC 3339 timestamps",
+		})
+	}
+
+	ctx := c.Request().Context()
+	if !at.IsZero() {
+		point, err := h.ratings.RatingAt(ctx, itemID, at)
+		if err == rating.ErrNoHistory {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "No rating recorded by then",
+			})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Internal server error",
+			})
+		}
+		return c.JSON(http.StatusOK, point)
+	}
+
+	points, err := h.ratings.History(ctx, itemID, from, to)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	return c.JSON(http.StatusOK, points)
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultNearbyRadius = 2000
+
+// maxNearbyLimit is the most restaurants one nearby search returns.
+const maxNearbyLimit = 100
+
+type RestaurantHandler struct {
+	restaurants *db.RestaurantStore
+}
+
+func NewRestaurantHandler(restaurants *db.RestaurantStore) *RestaurantHandler {
+	return &RestaurantHandler{restaurants: restaurants}
+}
+
+// Nearby finds restaurants around the lat and lon query parameters, within
+// radius meters. cuisine, which may be repeated or comma separated,
+// min_price, max_price and open_now narrow the search, and limit caps how
+// many are returned, up to maxNearbyLimit.
+func (h *RestaurantHandler) Nearby(c echo.Context) error {
+	lat, err := strconv.ParseFloat(c.QueryParam("lat"), 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid latitude")
+	}
+	lon, err := strconv.ParseFloat(c.QueryParam("lon"), 64)
+	if err != nil {
+		return h.respondError(c, http.StatusBadRequest, "Invalid longitude")
+	}
+	radius := defaultNearbyRadius
+	if r := c.QueryParam("radius"); r != "" {
+		if radius, err = strconv.Atoi(r); err != nil {
+			return h.respondError(c, http.StatusBadRequest, "Invalid radius")
+		}
+	}
+
+	var filter db.NearbyFilter
+	for _, cuisines := range c.QueryParams()["cuisine"] {
+		filter.Cuisines = append(filter.Cuisines, strings.Split(cuisines, ",")...)
+	}
+	filter.MinPrice, _ = strconv.Atoi(c.QueryParam("min_price"))
+	filter.MaxPrice, _ = strconv.Atoi(c.QueryParam("max_price"))
+	if open, _ := strconv.ParseBool(c.QueryParam("open_now")); open {
+		filter.OpenAt = time.Now()
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		if filter.Limit, err = strconv.Atoi(l); err != nil || filter.Limit < 0 {
+			return h.respondError(c, http.StatusBadRequest, "Invalid limit")
+		}
+	}
+	filter.Limit = min(filter.Limit, maxNearbyLimit)
+
+	restaurants, err := h.restaurants.Nearby(c.Request().Context(), lat, lon, radius, filter)
+	if err != nil {
+		if err == db.ErrInvalidRestaurant {
+			return h.respondError(c, http.StatusBadRequest, "Invalid search")
+		}
+		c.Logger().Error(err)
+		return h.respondError(c, http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, restaurants)
+}
+
+func (h *RestaurantHandler) respondError(c echo.Context, status int, message string) error {
+	return c.JSON(status, map[string]string{
+		"error": message,
+	})
+}
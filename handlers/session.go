@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+var errNotLoggedIn = errors.New("not logged in")
+
+// currentUser returns the database user for the logged in session. Users
+// sign in through Google, so their row is created the first time they need
+// one.
+func currentUser(c echo.Context, users *db.UserStore) (*db.User, error) {
+	authenticated, _ := c.Get("authenticated").(bool)
+	email, _ := c.Get("user_email").(string)
+	if !authenticated || email == "" {
+		return nil, errNotLoggedIn
+	}
+
+	ctx := c.Request().Context()
+	user, err := users.GetByEmail(ctx, email)
+	if err != db.ErrUserNotFound {
+		return user, err
+	}
+
+	name, _ := c.Get("user_name").(string)
+	if name == "" {
+		name = email
+	}
+	user, err = users.Create(ctx, email, name)
+	if err == db.ErrDuplicateEmail {
+		return users.GetByEmail(ctx, email)
+	}
+	return user, err
+}
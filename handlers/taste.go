@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/taste"
+	"github.com/labstack/echo/v4"
+)
+
+type TasteHandler struct {
+	taste  *taste.Service
+	users  *db.UserStore
+	groups *db.GroupStore
+}
+
+func NewTasteHandler(taste *taste.Service, users *db.UserStore, groups *db.GroupStore) *TasteHandler {
+	return &TasteHandler{taste: taste, users: users, groups: groups}
+}
+
+// Recommendations suggests items for the user in the path, who must be the
+// logged in user. The limit query parameter caps how many are returned.
+func (h *TasteHandler) Recommendations(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid user id",
+		})
+	}
+
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Log in to see recommendations",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	if user.ID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Recommendations are only available for yourself",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	recs, err := h.taste.Recommendations(c.Request().Context(), userID, limit)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	if recs == nil {
+		recs = []taste.Recommendation{}
+	}
+	return c.JSON(http.StatusOK, recs)
+}
+
+// Similarity scores how closely the logged in user, who must be the user in
+// the path, agrees with the other user. The places they agree and disagree
+// on show the other user's ratings, so they are only listed for users who
+// share a group. Anyone else gets the score alone.
+func (h *TasteHandler) Similarity(c echo.Context) error {
+	userID, err1 := strconv.Atoi(c.Param("id"))
+	otherID, err2 := strconv.Atoi(c.Param("other"))
+	if err1 != nil || err2 != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid user id",
+		})
+	}
+
+	user, err := currentUser(c, h.users)
+	if err == errNotLoggedIn {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Log in to compare tastes",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	if user.ID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "You can only compare your own taste",
+		})
+	}
+
+	ctx := c.Request().Context()
+	sim, err := h.taste.Similarity(ctx, userID, otherID)
+	if err == taste.ErrNoOverlap {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "You have not compared any of the same places yet",
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+
+	shared, err := h.groups.ShareGroup(ctx, userID, otherID)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Internal server error",
+		})
+	}
+	if !shared {
+		sim.Agree, sim.Disagree = []taste.Place{}, []taste.Place{}
+	}
+	return c.JSON(http.StatusOK, sim)
+}
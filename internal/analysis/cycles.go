@@ -0,0 +1,210 @@
+// Package analysis looks for places where pairwise results disagree with
+// each other, which is where a ranking built from them is least reliable.
+package analysis
+
+import (
+	"sort"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+// Graph is the directed win graph. There is an edge from a to b when a has
+// scored more than b across all of their matchups, draws counting half.
+type Graph struct {
+	nodes []int
+	// scores[a][b] is the total score a has taken from matchups against b.
+	scores map[int]map[int]float64
+	out    map[int][]int
+}
+
+func NewGraph(matchups []rating.Matchup) *Graph {
+	g := &Graph{
+		scores: make(map[int]map[int]float64),
+		out:    make(map[int][]int),
+	}
+	for _, m := range matchups {
+		score, ok := m.Score()
+		if !ok || m.Item1ID == m.Item2ID {
+			continue
+		}
+		g.add(m.Item1ID, m.Item2ID, score)
+		g.add(m.Item2ID, m.Item1ID, 1-score)
+	}
+
+	for a := range g.scores {
+		g.nodes = append(g.nodes, a)
+		for b := range g.scores[a] {
+			if g.scores[a][b] > g.scores[b][a] {
+				g.out[a] = append(g.out[a], b)
+			}
+		}
+		sort.Ints(g.out[a])
+	}
+	sort.Ints(g.nodes)
+	return g
+}
+
+func (g *Graph) add(a, b int, score float64) {
+	if g.scores[a] == nil {
+		g.scores[a] = make(map[int]float64)
+	}
+	g.scores[a][b] += score
+}
+
+// Margin is the share of the score between a and b that a took, less a half,
+// so it runs from 0 for an even record up to 0.5 for a clean sweep. It is
+// negative when b has the better record.
+func (g *Graph) Margin(a, b int) float64 {
+	ab, ba := g.scores[a][b], g.scores[b][a]
+	if ab+ba == 0 {
+		return 0
+	}
+	return ab/(ab+ba) - 0.5
+}
+
+// Games is how many matchups a and b have been in together.
+func (g *Graph) Games(a, b int) int {
+	return int(g.scores[a][b] + g.scores[b][a] + 0.5)
+}
+
+func (g *Graph) Nodes() int {
+	return len(g.nodes)
+}
+
+func (g *Graph) Edges() int {
+	n := 0
+	for _, out := range g.out {
+		n += len(out)
+	}
+	return n
+}
+
+// Components returns the strongly connected components with more than one
+// item, largest first. Every item in one of them is part of a cycle, so none
+// of them can be placed above or below the others without contradicting some
+// result.
+func (g *Graph) Components() [][]int {
+	t := tarjan{
+		g:       g,
+		index:   make(map[int]int),
+		lowlink: make(map[int]int),
+		onStack: make(map[int]bool),
+	}
+	for _, v := range g.nodes {
+		if _, seen := t.index[v]; !seen {
+			t.connect(v)
+		}
+	}
+
+	sort.SliceStable(t.components, func(i, j int) bool {
+		if len(t.components[i]) == len(t.components[j]) {
+			return t.components[i][0] < t.components[j][0]
+		}
+		return len(t.components[i]) > len(t.components[j])
+	})
+	return t.components
+}
+
+// tarjan holds the state for Tarjan's strongly connected components
+// algorithm.
+type tarjan struct {
+	g          *Graph
+	next       int
+	index      map[int]int
+	lowlink    map[int]int
+	stack      []int
+	onStack    map[int]bool
+	components [][]int
+}
+
+func (t *tarjan) connect(v int) {
+	t.index[v] = t.next
+	t.lowlink[v] = t.next
+	t.next++
+	t.stack = append(t.stack, v)
+	t.onStack[v] = true
+
+	for _, w := range t.g.out[v] {
+		if _, seen := t.index[w]; !seen {
+			t.connect(w)
+			t.lowlink[v] = min(t.lowlink[v], t.lowlink[w])
+		} else if t.onStack[w] {
+			t.lowlink[v] = min(t.lowlink[v], t.index[w])
+		}
+	}
+
+	if t.lowlink[v] != t.index[v] {
+		return
+	}
+
+	var component []int
+	for {
+		w := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[w] = false
+		component = append(component, w)
+		if w == v {
+			break
+		}
+	}
+	if len(component) > 1 {
+		sort.Ints(component)
+		t.components = append(t.components, component)
+	}
+}
+
+// Triple is a cycle of three items where A beat B, B beat C and C beat A.
+type Triple struct {
+	Items [3]int
+	// Margins holds the margin of each win in the cycle, A over B first.
+	Margins [3]float64
+	// Strength is the smallest of the margins. A triple is only as
+	// contradictory as its weakest link, which could flip with one more
+	// matchup.
+	Strength float64
+	// Games is the number of matchups behind the three results.
+	Games int
+}
+
+// ViolatedTriples returns up to limit three-item cycles, strongest first.
+// Each cycle is reported once, starting from its lowest item id.
+func (g *Graph) ViolatedTriples(limit int) []Triple {
+	var triples []Triple
+	for _, component := range g.Components() {
+		in := make(map[int]bool, len(component))
+		for _, id := range component {
+			in[id] = true
+		}
+
+		for _, a := range component {
+			for _, b := range g.out[a] {
+				if b < a || !in[b] {
+					continue
+				}
+				for _, c := range g.out[b] {
+					if c < a || !in[c] || g.Margin(c, a) <= 0 {
+						continue
+					}
+					t := Triple{
+						Items:   [3]int{a, b, c},
+						Margins: [3]float64{g.Margin(a, b), g.Margin(b, c), g.Margin(c, a)},
+						Games:   g.Games(a, b) + g.Games(b, c) + g.Games(c, a),
+					}
+					t.Strength = min(t.Margins[0], t.Margins[1], t.Margins[2])
+					triples = append(triples, t)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(triples, func(i, j int) bool {
+		if triples[i].Strength == triples[j].Strength {
+			return triples[i].Games > triples[j].Games
+		}
+		return triples[i].Strength > triples[j].Strength
+	})
+	if limit > 0 && len(triples) > limit {
+		triples = triples[:limit]
+	}
+	return triples
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+func win(winner, loser int) rating.Matchup {
+	return rating.Matchup{Item1ID: winner, Item2ID: loser, WinnerID: winner}
+}
+
+func TestCycles(t *testing.T) {
+	tests := []struct {
+		name       string
+		matchups   []rating.Matchup
+		components [][]int
+		triples    [][3]int
+	}{
+		{
+			name:       "three-cycle",
+			matchups:   []rating.Matchup{win(1, 2), win(2, 3), win(3, 1)},
+			components: [][]int{{1, 2, 3}},
+			triples:    [][3]int{{1, 2, 3}},
+		},
+		{
+			name:     "no cycles",
+			matchups: []rating.Matchup{win(1, 2), win(2, 3), win(1, 3), win(3, 4)},
+		},
+		{
+			name:     "split votes",
+			matchups: []rating.Matchup{win(1, 2), win(2, 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGraph(tt.matchups)
+
+			components := g.Components()
+			if !reflect.DeepEqual(components, tt.components) {
+				t.Errorf("Components() = %v, want %v", components, tt.components)
+			}
+
+			var triples [][3]int
+			for _, triple := range g.ViolatedTriples(0) {
+				triples = append(triples, triple.Items)
+			}
+			if !reflect.DeepEqual(triples, tt.triples) {
+				t.Errorf("ViolatedTriples() = %v, want %v", triples, tt.triples)
+			}
+		})
+	}
+}
+
+func TestTripleStrength(t *testing.T) {
+	// 1 beat 2 twice, 2 beat 3 once and 3 beat 1 three times to one.
+	g := NewGraph([]rating.Matchup{
+		win(1, 2), win(1, 2),
+		win(2, 3),
+		win(3, 1), win(3, 1), win(3, 1), win(1, 3),
+	})
+
+	triples := g.ViolatedTriples(0)
+	if len(triples) != 1 {
+		t.Fatalf("ViolatedTriples() = %+v, want one", triples)
+	}
+	got := triples[0]
+	if want := [3]float64{0.5, 0.5, 0.25}; got.Margins != want {
+		t.Errorf("margins = %v, want %v", got.Margins, want)
+	}
+	if got.Strength != 0.25 || got.Games != 7 {
+		t.Errorf("strength = %v over %d games, want 0.25 over 7", got.Strength, got.Games)
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/lib/pq"
+)
+
+const defaultTripleLimit = 20
+
+type Item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type ReportComponent struct {
+	Items []Item `json:"items"`
+}
+
+type ReportTriple struct {
+	Items    [3]Item    `json:"items"`
+	Margins  [3]float64 `json:"margins"`
+	Strength float64    `json:"strength"`
+	Games    int        `json:"games"`
+}
+
+// Report summarises the cycles in the win graph.
+type Report struct {
+	Items    int `json:"items"`
+	Edges    int `json:"edges"`
+	Matchups int `json:"matchups"`
+	// Cyclic is how many items sit in a component with at least one other.
+	Cyclic     int               `json:"cyclic"`
+	Components []ReportComponent `json:"components"`
+	Triples    []ReportTriple    `json:"triples"`
+}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Cycles builds the win graph from every matchup and reports its cycles,
+// with up to limit of the most violated triples.
+func (s *Service) Cycles(ctx context.Context, limit int) (*Report, error) {
+	if limit <= 0 {
+		limit = defaultTripleLimit
+	}
+
+	matchups, err := rating.LoadMatchups(ctx, s.db, rating.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGraph(matchups)
+	components := g.Components()
+	triples := g.ViolatedTriples(limit)
+
+	names, err := s.names(ctx, components)
+	if err != nil {
+		return nil, err
+	}
+	item := func(id int) Item {
+		return Item{ID: id, Name: names[id]}
+	}
+
+	report := &Report{
+		Items:      g.Nodes(),
+		Edges:      g.Edges(),
+		Matchups:   len(matchups),
+		Components: []ReportComponent{},
+		Triples:    []ReportTriple{},
+	}
+	for _, component := range components {
+		rc := ReportComponent{Items: make([]Item, len(component))}
+		for i, id := range component {
+			rc.Items[i] = item(id)
+		}
+		report.Cyclic += len(component)
+		report.Components = append(report.Components, rc)
+	}
+	for _, t := range triples {
+		report.Triples = append(report.Triples, ReportTriple{
+			Items:    [3]Item{item(t.Items[0]), item(t.Items[1]), item(t.Items[2])},
+			Margins:  t.Margins,
+			Strength: t.Strength,
+			Games:    t.Games,
+		})
+	}
+	return report, nil
+}
+
+// names looks up the name of every item in the components. Items in a
+// triple always belong to one of them.
+func (s *Service) names(ctx context.Context, components [][]int) (map[int]string, error) {
+	var ids []int64
+	for _, component := range components {
+		for _, id := range component {
+			ids = append(ids, int64(id))
+		}
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, name FROM items WHERE id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -28,6 +30,7 @@ func AuthContext(next echo.HandlerFunc) echo.HandlerFunc {
 				"authenticated": false,
 				"user_name":     "",
 				"user_id":       "",
+				"user_email":    "",
 			})
 			return next(c)
 		}
@@ -40,6 +43,7 @@ func AuthContext(next echo.HandlerFunc) echo.HandlerFunc {
 				"authenticated": false,
 				"user_name":     "",
 				"user_id":       "",
+				"user_email":    "",
 			})
             return next(c)
 		}
@@ -48,6 +52,7 @@ func AuthContext(next echo.HandlerFunc) echo.HandlerFunc {
             "authenticated": true,
             "user_name":    session.Values["name"],
             "user_id": userID,
+            "user_email": session.Values["email"],
         })
 
 		return next(c)
@@ -63,3 +68,27 @@ func RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// RequireAdmin only lets through users whose email is listed in the comma
+// separated ADMIN_EMAILS environment variable. It relies on AuthContext
+// having run first.
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	admins := make(map[string]bool)
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(strings.ToLower(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(c echo.Context) error {
+		authenticated, _ := c.Get("authenticated").(bool)
+		if !authenticated {
+			return c.Redirect(http.StatusTemporaryRedirect, "/auth/google")
+		}
+		email, _ := c.Get("user_email").(string)
+		if !admins[strings.ToLower(email)] {
+			return c.String(http.StatusForbidden, "Forbidden")
+		}
+		return next(c)
+	}
+}
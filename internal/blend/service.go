@@ -0,0 +1,128 @@
+package blend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a new blend link stays valid.
+	DefaultTTL = 7 * 24 * time.Hour
+	// revocationRefresh is how often revocations made by other instances are
+	// picked up. Until then a revoked link can still be opened there.
+	revocationRefresh = time.Minute
+)
+
+var ErrNotCreator = errors.New("only the creator can revoke a blend link")
+
+// Service issues blend links and keeps the revoked ones in memory, so
+// opening a link never waits on the database except for the occasional
+// refresh of the revocation list.
+type Service struct {
+	signer *Signer
+	db     *sql.DB
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	revoked   map[string]time.Time
+	refreshed time.Time
+}
+
+func NewService(db *sql.DB, signer *Signer, ttl time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Service{signer: signer, db: db, ttl: ttl}
+}
+
+// Create issues a new link from the user.
+func (s *Service) Create(userID int) (string, Link, error) {
+	return s.signer.Issue(userID, s.ttl)
+}
+
+// Open returns the link in the token if it is signed, unexpired and not
+// revoked.
+func (s *Service) Open(ctx context.Context, token string) (Link, error) {
+	now := time.Now()
+	link, err := s.signer.Verify(token, now)
+	if err != nil {
+		return Link{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.refreshed) >= revocationRefresh {
+		if err := s.refresh(ctx, now); err != nil {
+			return Link{}, err
+		}
+	}
+	if _, ok := s.revoked[link.ID]; ok {
+		return Link{}, ErrLinkRevoked
+	}
+	return link, nil
+}
+
+// Revoke stops the link in the token from opening. Only the user who
+// created it can revoke it.
+func (s *Service) Revoke(ctx context.Context, userID int, token string) error {
+	link, err := s.signer.Parse(token)
+	if err != nil {
+		return err
+	}
+	if link.CreatorID != userID {
+		return ErrNotCreator
+	}
+
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO blend_link_revocations (link_id, user_id, expires_at, revoked_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (link_id) DO NOTHING`,
+		link.ID, userID, link.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revoked != nil {
+		s.revoked[link.ID] = link.ExpiresAt
+	}
+	return nil
+}
+
+// refresh reloads the revocations of links that have not expired yet.
+// Expired links fail verification anyway, so there is no need to keep them.
+func (s *Service) refresh(ctx context.Context, now time.Time) error {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT link_id, expires_at FROM blend_link_revocations WHERE expires_at > $1`,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	revoked := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var expires time.Time
+		if err := rows.Scan(&id, &expires); err != nil {
+			return err
+		}
+		revoked[id] = expires
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.revoked = revoked
+	s.refreshed = now
+	return nil
+}
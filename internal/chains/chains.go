@@ -0,0 +1,116 @@
+// Package chains rates restaurant chains from their branches.
+package chains
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+var ErrUnknownMethod = errors.New("unknown chain rating method")
+
+// Method is how a chain's rating is worked out from its branches.
+type Method string
+
+const (
+	// Pooled treats every branch as the chain itself and rates chains from
+	// the matchups between branches of different chains. A chain is judged
+	// on the same comparisons whichever branch they were made at.
+	Pooled Method = "pooled"
+	// Branches combines the branches' own ratings, giving more say to the
+	// ones that are rated with more certainty.
+	Branches Method = "branches"
+)
+
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(s); m {
+	case "":
+		return Pooled, nil
+	case Pooled, Branches:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownMethod, s)
+	}
+}
+
+// pool rewrites matchups between branches as matchups between their chains.
+// Matchups involving a place outside any chain, or two branches of the same
+// chain, say nothing about chains against each other and are dropped.
+func pool(matchups []rating.Matchup, chainOf map[int]int) []rating.Matchup {
+	pooled := make([]rating.Matchup, 0, len(matchups))
+	for _, m := range matchups {
+		chain1, ok1 := chainOf[m.Item1ID]
+		chain2, ok2 := chainOf[m.Item2ID]
+		if !ok1 || !ok2 || chain1 == chain2 {
+			continue
+		}
+
+		switch m.WinnerID {
+		case m.Item1ID:
+			m.WinnerID = chain1
+		case m.Item2ID:
+			m.WinnerID = chain2
+		}
+		m.Item1ID, m.Item2ID = chain1, chain2
+		pooled = append(pooled, m)
+	}
+	return pooled
+}
+
+// combine rates each chain as the precision weighted mean of its branches'
+// ratings, so a branch with a deviation half another's counts four times as
+// much. The chain's deviation is that of the combined estimate.
+func combine(ratings map[int]rating.Rating, chainOf map[int]int) map[int]rating.Rating {
+	type sums struct {
+		weighted, precision float64
+		matches             int
+	}
+	totals := make(map[int]*sums)
+	for itemID, r := range ratings {
+		chainID, ok := chainOf[itemID]
+		if !ok {
+			continue
+		}
+		t := totals[chainID]
+		if t == nil {
+			t = &sums{}
+			totals[chainID] = t
+		}
+		precision := 1 / math.Max(r.Deviation*r.Deviation, 1)
+		t.weighted += r.Rating * precision
+		t.precision += precision
+		t.matches += r.Matches
+	}
+
+	combined := make(map[int]rating.Rating, len(totals))
+	for chainID, t := range totals {
+		combined[chainID] = rating.Rating{
+			ItemID:    chainID,
+			Rating:    t.weighted / t.precision,
+			Deviation: math.Sqrt(1 / t.precision),
+			Matches:   t.matches,
+		}
+	}
+	return combined
+}
+
+// spread is the standard deviation of the branches' ratings, which is how
+// much it matters which branch you go to.
+func spread(branches []Branch) float64 {
+	if len(branches) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, b := range branches {
+		mean += b.Rating
+	}
+	mean /= float64(len(branches))
+
+	var variance float64
+	for _, b := range branches {
+		variance += (b.Rating - mean) * (b.Rating - mean)
+	}
+	return math.Sqrt(variance / float64(len(branches)))
+}
@@ -0,0 +1,290 @@
+package chains
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+// poolRefresh is how long pooled chain ratings are reused when no matchup
+// has changed, so branches added to a chain are picked up.
+const poolRefresh = 10 * time.Minute
+
+type RatingSource interface {
+	Ratings(ctx context.Context) (map[int]rating.Rating, error)
+}
+
+type Entry struct {
+	Rank        int     `json:"rank"`
+	ChainID     int     `json:"chain_id"`
+	Name        string  `json:"name"`
+	Rating      float64 `json:"rating"`
+	Deviation   float64 `json:"deviation"`
+	Provisional bool    `json:"provisional"`
+	Comparisons int     `json:"comparisons"`
+	Branches    int     `json:"branches"`
+}
+
+type Board struct {
+	Method  Method  `json:"method"`
+	Entries []Entry `json:"entries"`
+}
+
+type Branch struct {
+	ItemID  int    `json:"item_id"`
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	// Rated is false for branches nobody has compared yet, which are left
+	// out of the chain's spread.
+	Rated       bool    `json:"rated"`
+	Rating      float64 `json:"rating"`
+	Deviation   float64 `json:"deviation"`
+	Provisional bool    `json:"provisional"`
+	Comparisons int     `json:"comparisons"`
+}
+
+// Detail is a chain's rating alongside those of each of its branches.
+type Detail struct {
+	db.Chain
+	Method Method `json:"method"`
+	// Rank is the chain's place on the chain leaderboard, or zero if it is
+	// not on it yet.
+	Rank        int      `json:"rank"`
+	Rating      float64  `json:"rating"`
+	Deviation   float64  `json:"deviation"`
+	Provisional bool     `json:"provisional"`
+	Comparisons int      `json:"comparisons"`
+	Branches    []Branch `json:"branches"`
+	// Spread is the standard deviation of the rated branches' ratings.
+	Spread float64 `json:"spread"`
+}
+
+type Service struct {
+	db       *sql.DB
+	ratings  RatingSource
+	newRater func() rating.Rater
+
+	mu             sync.Mutex
+	pooled         map[int]rating.Rating
+	pooledComputed time.Time
+}
+
+func NewService(db *sql.DB, ratings RatingSource, newRater func() rating.Rater) *Service {
+	return &Service{db: db, ratings: ratings, newRater: newRater}
+}
+
+// Invalidate marks the pooled ratings stale, typically after a matchup
+// change.
+func (s *Service) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pooled = nil
+}
+
+// Board ranks every chain that has a rating.
+func (s *Service) Board(ctx context.Context, method Method) (*Board, error) {
+	members, err := s.members(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := s.chainRatings(ctx, method, members)
+	if err != nil {
+		return nil, err
+	}
+
+	board := &Board{Method: method, Entries: []Entry{}}
+	for _, r := range rating.Ranked(ratings) {
+		// Pooled ratings may be a little older than the chains' branches.
+		chain, ok := members.chains[r.ItemID]
+		if !ok {
+			continue
+		}
+		board.Entries = append(board.Entries, Entry{
+			Rank:        len(board.Entries) + 1,
+			ChainID:     r.ItemID,
+			Name:        chain.name,
+			Rating:      r.Rating,
+			Deviation:   r.Deviation,
+			Provisional: r.Provisional(),
+			Comparisons: r.Matches,
+			Branches:    len(chain.branches),
+		})
+	}
+	return board, nil
+}
+
+// Chain returns the chain with its rating and its branches, best first.
+func (s *Service) Chain(ctx context.Context, chainID int, method Method) (*Detail, error) {
+	var d Detail
+	var website, description sql.NullString
+	var founded sql.NullInt64
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, name, website, description, founded_year, created_at
+		FROM restaurant_chains
+		WHERE id = $1`,
+		chainID,
+	).Scan(
+		&d.ID,
+		&d.Name,
+		&website,
+		&description,
+		&founded,
+		&d.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, db.ErrChainNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Website = website.String
+	d.Description = description.String
+	d.FoundedYear = int(founded.Int64)
+	d.Method = method
+
+	members, err := s.members(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := s.chainRatings(ctx, method, members)
+	if err != nil {
+		return nil, err
+	}
+	rank := 0
+	for _, r := range rating.Ranked(ratings) {
+		if _, ok := members.chains[r.ItemID]; !ok {
+			continue
+		}
+		rank++
+		if r.ItemID == chainID {
+			d.Rank = rank
+			d.Rating = r.Rating
+			d.Deviation = r.Deviation
+			d.Provisional = r.Provisional()
+			d.Comparisons = r.Matches
+		}
+	}
+
+	global, err := s.ratings.Ratings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.Branches = []Branch{}
+	var rated []Branch
+	var branches []Branch
+	if chain, ok := members.chains[chainID]; ok {
+		branches = chain.branches
+	}
+	for _, b := range branches {
+		if r, ok := global[b.ItemID]; ok {
+			b.Rated = true
+			b.Rating = r.Rating
+			b.Deviation = r.Deviation
+			b.Provisional = r.Provisional()
+			b.Comparisons = r.Matches
+			rated = append(rated, b)
+		}
+		d.Branches = append(d.Branches, b)
+	}
+	sort.SliceStable(d.Branches, func(i, j int) bool {
+		if d.Branches[i].Rated != d.Branches[j].Rated {
+			return d.Branches[i].Rated
+		}
+		return d.Branches[i].Rating > d.Branches[j].Rating
+	})
+	d.Spread = spread(rated)
+	return &d, nil
+}
+
+func (s *Service) chainRatings(ctx context.Context, method Method, members *membership) (map[int]rating.Rating, error) {
+	if method == Branches {
+		global, err := s.ratings.Ratings(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return combine(global, members.chainOf), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pooled != nil && time.Since(s.pooledComputed) < poolRefresh {
+		return s.pooled, nil
+	}
+
+	matchups, err := rating.LoadMatchups(ctx, s.db, rating.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	r := s.newRater()
+	r.Process(pool(matchups, members.chainOf))
+
+	s.pooled = r.Ratings()
+	s.pooledComputed = time.Now()
+	return s.pooled, nil
+}
+
+type chainBranches struct {
+	name     string
+	branches []Branch
+}
+
+// membership is which chain each branch belongs to.
+type membership struct {
+	chainOf map[int]int
+	chains  map[int]*chainBranches
+}
+
+// members reads every chain's branches. Archived branches are left out, so
+// a branch that has closed no longer counts towards its chain.
+func (s *Service) members(ctx context.Context) (*membership, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT c.id, c.name, i.id, i.name, COALESCE(rm.address, '')
+		FROM restaurant_chains c
+		JOIN restaurant_metadata rm ON rm.chain_id = c.id
+		JOIN items i ON i.id = rm.item_id AND i.archived_at IS NULL
+		ORDER BY c.id, i.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	m := &membership{chainOf: make(map[int]int), chains: make(map[int]*chainBranches)}
+	for rows.Next() {
+		var chainID int
+		var chainName string
+		var b Branch
+		err := rows.Scan(
+			&chainID,
+			&chainName,
+			&b.ItemID,
+			&b.Name,
+			&b.Address,
+		)
+		if err != nil {
+			return nil, err
+		}
+		chain := m.chains[chainID]
+		if chain == nil {
+			chain = &chainBranches{name: chainName}
+			m.chains[chainID] = chain
+		}
+		chain.branches = append(chain.branches, b)
+		m.chainOf[b.ItemID] = chainID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
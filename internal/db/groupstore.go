@@ -0,0 +1,681 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrInvalidGroup        = errors.New("invalid group data")
+	ErrNotInvited          = errors.New("user has not been invited to the group")
+	ErrAlreadyMember       = errors.New("user is already a member of the group")
+	ErrNotMember           = errors.New("user is not a member of the group")
+	ErrNotGroupCreator     = errors.New("only the group's creator can do that")
+	ErrCreatorCannotLeave  = errors.New("the group's creator cannot leave it")
+	ErrInvalidTransition   = errors.New("invalid group status transition")
+	ErrInvalidAvailability = errors.New("invalid availability")
+)
+
+type GroupStatus string
+
+const (
+	GroupPlanning GroupStatus = "planning"
+	GroupDecided  GroupStatus = "decided"
+	GroupDone     GroupStatus = "done"
+)
+
+// Next returns the status a group moves on to from s, or "" if s is the
+// last one.
+func (s GroupStatus) Next() GroupStatus {
+	switch s {
+	case GroupPlanning:
+		return GroupDecided
+	case GroupDecided:
+		return GroupDone
+	default:
+		return ""
+	}
+}
+
+type Group struct {
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	CreatedBy int           `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+	EventTime *time.Time    `json:"event_time,omitempty"`
+	Status    GroupStatus   `json:"status"`
+	Members   []GroupMember `json:"members,omitempty"`
+	Invites   []GroupInvite `json:"invites,omitempty"`
+}
+
+// IsMember reports whether the user is in the group's loaded members.
+func (g *Group) IsMember(userID int) bool {
+	for _, m := range g.Members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+type GroupMember struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// SearchRadiusMeters is how far the member is willing to travel. Zero
+	// means they have not said.
+	SearchRadiusMeters int          `json:"search_radius_meters,omitempty"`
+	Availability       Availability `json:"availability"`
+	JoinedAt           time.Time    `json:"joined_at"`
+}
+
+// maxAvailabilityWindows keeps a member's availability to a size that is
+// cheap to overlap with everyone else's.
+const maxAvailabilityWindows = 100
+
+// Availability is the shape of group_members.availability: the times a
+// member is free, for example
+//
+//	{"windows": [{"start": "2024-06-07T18:00:00Z", "end": "2024-06-07T23:00:00Z"}]}
+type Availability struct {
+	Windows []AvailabilityWindow `json:"windows"`
+}
+
+// AvailabilityWindow runs from Start up to, but not including, End.
+type AvailabilityWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Normalise checks every window ends after it starts, then sorts them and
+// joins the ones that overlap or touch.
+func (a Availability) Normalise() (Availability, error) {
+	windows := make([]AvailabilityWindow, 0, len(a.Windows))
+	for _, w := range a.Windows {
+		if w.Start.IsZero() || !w.End.After(w.Start) {
+			return Availability{}, ErrInvalidAvailability
+		}
+		windows = append(windows, AvailabilityWindow{Start: w.Start.UTC(), End: w.End.UTC()})
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+
+	merged := windows[:0]
+	for _, w := range windows {
+		if n := len(merged); n > 0 && !w.Start.After(merged[n-1].End) {
+			if w.End.After(merged[n-1].End) {
+				merged[n-1].End = w.End
+			}
+			continue
+		}
+		merged = append(merged, w)
+	}
+	if len(merged) > maxAvailabilityWindows {
+		return Availability{}, ErrInvalidAvailability
+	}
+	return Availability{Windows: merged}, nil
+}
+
+type GroupInvite struct {
+	GroupID   int       `json:"group_id"`
+	GroupName string    `json:"group_name"`
+	Email     string    `json:"email"`
+	InvitedBy int       `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GroupStore struct {
+	db *sql.DB
+}
+
+func NewGroupStore(db *sql.DB) *GroupStore {
+	return &GroupStore{db: db}
+}
+
+// Create makes a new group in the planning state with its creator as the
+// only member.
+func (s *GroupStore) Create(ctx context.Context, creatorID int, name string, eventTime *time.Time) (*Group, error) {
+	name = strings.TrimSpace(name)
+	if creatorID <= 0 || name == "" {
+		return nil, ErrInvalidGroup
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var g Group
+	var event sql.NullTime
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO groups (name, created_by, created_at, event_time, status)
+		VALUES ($1, $2, CURRENT_TIMESTAMP, $3, $4)
+		RETURNING id, name, created_by, created_at, event_time, status`,
+		name, creatorID, eventTime, GroupPlanning,
+	).Scan(
+		&g.ID,
+		&g.Name,
+		&g.CreatedBy,
+		&g.CreatedAt,
+		&event,
+		&g.Status,
+	)
+	if err != nil {
+		if isPgForeignKeyViolation(err) {
+			return nil, ErrInvalidGroup
+		}
+		return nil, err
+	}
+	if event.Valid {
+		g.EventTime = &event.Time
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO group_members (group_id, user_id, joined_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)`,
+		g.ID, creatorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, g.ID)
+}
+
+// GetByID returns the group with its members and outstanding invites.
+func (s *GroupStore) GetByID(ctx context.Context, id int) (*Group, error) {
+	var g Group
+	var event sql.NullTime
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, name, COALESCE(created_by, 0), created_at, event_time, status
+		FROM groups
+		WHERE id = $1`,
+		id,
+	).Scan(
+		&g.ID,
+		&g.Name,
+		&g.CreatedBy,
+		&g.CreatedAt,
+		&event,
+		&g.Status,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if event.Valid {
+		g.EventTime = &event.Time
+	}
+
+	if g.Members, err = s.members(ctx, id); err != nil {
+		return nil, err
+	}
+	if g.Invites, err = s.invites(ctx, `gi.group_id = $1`, id); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (s *GroupStore) members(ctx context.Context, groupID int) ([]GroupMember, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT gm.user_id, u.name, COALESCE(gm.search_radius_meters, 0), gm.availability, gm.joined_at
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = $1
+		ORDER BY gm.joined_at, gm.user_id`,
+		groupID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []GroupMember
+	for rows.Next() {
+		var m GroupMember
+		var availability []byte
+		err := rows.Scan(
+			&m.UserID,
+			&m.Name,
+			&m.SearchRadiusMeters,
+			&availability,
+			&m.JoinedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if availability != nil {
+			if err := json.Unmarshal(availability, &m.Availability); err != nil {
+				return nil, err
+			}
+		}
+		if m.Availability.Windows == nil {
+			m.Availability.Windows = []AvailabilityWindow{}
+		}
+		members = append(members, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (s *GroupStore) invites(ctx context.Context, where string, arg any) ([]GroupInvite, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT gi.group_id, g.name, gi.email, COALESCE(gi.invited_by, 0), gi.created_at
+		FROM group_invites gi
+		JOIN groups g ON g.id = gi.group_id
+		WHERE `+where+`
+		ORDER BY gi.created_at, gi.group_id`,
+		arg,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []GroupInvite
+	for rows.Next() {
+		var inv GroupInvite
+		err := rows.Scan(
+			&inv.GroupID,
+			&inv.GroupName,
+			&inv.Email,
+			&inv.InvitedBy,
+			&inv.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, inv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+// ListForUser returns the groups the user is a member of, newest first,
+// without their members.
+func (s *GroupStore) ListForUser(ctx context.Context, userID int) ([]Group, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT g.id, g.name, COALESCE(g.created_by, 0), g.created_at, g.event_time, g.status
+		FROM groups g
+		JOIN group_members gm ON gm.group_id = g.id
+		WHERE gm.user_id = $1
+		ORDER BY g.created_at DESC, g.id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []Group
+	for rows.Next() {
+		var g Group
+		var event sql.NullTime
+		err := rows.Scan(
+			&g.ID,
+			&g.Name,
+			&g.CreatedBy,
+			&g.CreatedAt,
+			&event,
+			&g.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if event.Valid {
+			g.EventTime = &event.Time
+		}
+		groups = append(groups, g)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// ShareGroup reports whether the two users are members of the same group.
+func (s *GroupStore) ShareGroup(ctx context.Context, userID, otherID int) (bool, error) {
+	var shared bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1
+			FROM group_members a
+			JOIN group_members b ON b.group_id = a.group_id
+			WHERE a.user_id = $1 AND b.user_id = $2
+		)`,
+		userID, otherID,
+	).Scan(&shared)
+	return shared, err
+}
+
+// InvitesFor returns the invites waiting for the email address.
+func (s *GroupStore) InvitesFor(ctx context.Context, email string) ([]GroupInvite, error) {
+	return s.invites(ctx, `gi.email = $1`, strings.ToLower(email))
+}
+
+// Invite lets the owner of email join the group. Invites are by email so
+// people can be invited before they have signed in for the first time.
+func (s *GroupStore) Invite(ctx context.Context, groupID, inviterID int, email string) (*GroupInvite, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, ErrInvalidGroup
+	}
+	if err := s.requireMember(ctx, groupID, inviterID); err != nil {
+		return nil, err
+	}
+
+	var member bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM group_members gm
+			JOIN users u ON u.id = gm.user_id
+			WHERE gm.group_id = $1 AND lower(u.email) = $2
+		)`,
+		groupID, email,
+	).Scan(&member)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, ErrAlreadyMember
+	}
+
+	var inv GroupInvite
+	err = s.db.QueryRowContext(
+		ctx,
+		`WITH inserted AS (
+			INSERT INTO group_invites (group_id, email, invited_by, created_at)
+			VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+			ON CONFLICT (group_id, email) DO UPDATE SET invited_by = EXCLUDED.invited_by
+			RETURNING group_id, email, invited_by, created_at
+		)
+		SELECT i.group_id, g.name, i.email, i.invited_by, i.created_at
+		FROM inserted i
+		JOIN groups g ON g.id = i.group_id`,
+		groupID, email, inviterID,
+	).Scan(
+		&inv.GroupID,
+		&inv.GroupName,
+		&inv.Email,
+		&inv.InvitedBy,
+		&inv.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+// Join accepts the user's invite to the group.
+func (s *GroupStore) Join(ctx context.Context, groupID, userID int) (*Group, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM group_invites gi
+		USING users u
+		WHERE gi.group_id = $1 AND u.id = $2 AND gi.email = lower(u.email)`,
+		groupID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		if _, err := s.GetByID(ctx, groupID); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotInvited
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO group_members (group_id, user_id, joined_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)`,
+		groupID, userID,
+	)
+	if err != nil {
+		if isPgUniqueViolation(err) {
+			return nil, ErrAlreadyMember
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, groupID)
+}
+
+// Leave removes the user from the group. The creator has to stay, as only
+// they can move the group's status on.
+func (s *GroupStore) Leave(ctx context.Context, groupID, userID int) error {
+	g, err := s.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if g.CreatedBy == userID {
+		return ErrCreatorCannotLeave
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`,
+		groupID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotMember
+	}
+
+	return nil
+}
+
+// SetStatus moves the group on to status. Only the creator can do it, and
+// only one step at a time: planning, then decided, then done.
+func (s *GroupStore) SetStatus(ctx context.Context, groupID, userID int, status GroupStatus) (*Group, error) {
+	g, err := s.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if g.CreatedBy != userID {
+		return nil, ErrNotGroupCreator
+	}
+	if status == "" || g.Status.Next() != status {
+		return nil, ErrInvalidTransition
+	}
+
+	// The status is checked again in the update in case it moved on since it
+	// was read.
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE groups SET status = $3 WHERE id = $1 AND status = $2`,
+		groupID, g.Status, status,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrInvalidTransition
+	}
+
+	g.Status = status
+	return g, nil
+}
+
+// SetSearchRadius sets how far the member is willing to travel from home.
+// Zero clears it.
+func (s *GroupStore) SetSearchRadius(ctx context.Context, groupID, userID, meters int) error {
+	if meters < 0 {
+		return ErrInvalidGroup
+	}
+	if err := s.requireMember(ctx, groupID, userID); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		`UPDATE group_members
+		SET search_radius_meters = NULLIF($3, 0)
+		WHERE group_id = $1 AND user_id = $2`,
+		groupID, userID, meters,
+	)
+	return err
+}
+
+// SetAvailability replaces the times the member is free.
+func (s *GroupStore) SetAvailability(ctx context.Context, groupID, userID int, availability Availability) (Availability, error) {
+	availability, err := availability.Normalise()
+	if err != nil {
+		return Availability{}, err
+	}
+	if err := s.requireMember(ctx, groupID, userID); err != nil {
+		return Availability{}, err
+	}
+
+	raw, err := json.Marshal(availability)
+	if err != nil {
+		return Availability{}, err
+	}
+	_, err = s.db.ExecContext(
+		ctx,
+		`UPDATE group_members
+		SET availability = $3
+		WHERE group_id = $1 AND user_id = $2`,
+		groupID, userID, raw,
+	)
+	if err != nil {
+		return Availability{}, err
+	}
+	return availability, nil
+}
+
+// GroupCandidate is a restaurant within reach of every member of a group.
+type GroupCandidate struct {
+	ItemID int    `json:"item_id"`
+	Name   string `json:"name"`
+}
+
+// Candidates returns the restaurants that are within every member's
+// search radius of their home. Members who have not set a radius or a home
+// location can go anywhere, so they rule nothing out. Restaurants without
+// coordinates are left out when anyone has a radius, since there is no way
+// to tell whether they are in range.
+func (s *GroupStore) Candidates(ctx context.Context, groupID int) ([]GroupCandidate, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`WITH limits AS (
+			SELECT gm.search_radius_meters AS radius,
+				ll_to_earth(p.home_location_lat, p.home_location_lon) AS home
+			FROM group_members gm
+			JOIN user_profiles p ON p.user_id = gm.user_id
+			WHERE gm.group_id = $1
+				AND gm.search_radius_meters IS NOT NULL
+				AND p.home_location_lat IS NOT NULL
+				AND p.home_location_lon IS NOT NULL
+		)
+		SELECT i.id, i.name
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id AND t.name = 'restaurant'
+		LEFT JOIN restaurant_metadata rm ON rm.item_id = i.id
+		WHERE i.archived_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM limits l
+			WHERE rm.latitude IS NULL
+				OR rm.longitude IS NULL
+				OR earth_distance(l.home, ll_to_earth(rm.latitude, rm.longitude)) > l.radius
+		)
+		ORDER BY i.id`,
+		groupID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []GroupCandidate
+	for rows.Next() {
+		var c GroupCandidate
+		if err := rows.Scan(&c.ItemID, &c.Name); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+func (s *GroupStore) requireMember(ctx context.Context, groupID, userID int) error {
+	var exists, member bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT
+			EXISTS (SELECT 1 FROM groups WHERE id = $1),
+			EXISTS (SELECT 1 FROM group_members WHERE group_id = $1 AND user_id = $2)`,
+		groupID, userID,
+	).Scan(&exists, &member)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrGroupNotFound
+	}
+	if !member {
+		return ErrNotMember
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGroupMembersAvailabilityRoundTrip(t *testing.T) {
+	database := testDB(t)
+	ctx := context.Background()
+	users := NewUserStore(database)
+	groups := NewGroupStore(database)
+
+	suffix := time.Now().UnixNano()
+	creator, err := users.Create(ctx, fmt.Sprintf("creator-%d@example.com", suffix), "Creator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.Exec(`DELETE FROM group_members WHERE user_id = $1`, creator.ID)
+		database.Exec(`DELETE FROM groups WHERE created_by = $1`, creator.ID)
+		database.Exec(`DELETE FROM users WHERE id = $1`, creator.ID)
+	})
+
+	group, err := groups.Create(ctx, creator.ID, "Dinner", nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(group.Members) != 1 || len(group.Members[0].Availability.Windows) != 0 {
+		t.Fatalf("new group members = %+v, want the creator with no availability", group.Members)
+	}
+
+	start := time.Date(2030, 6, 7, 18, 0, 0, 0, time.UTC)
+	want := Availability{Windows: []AvailabilityWindow{
+		{Start: start.Add(24 * time.Hour), End: start.Add(27 * time.Hour)},
+		{Start: start, End: start.Add(2 * time.Hour)},
+		{Start: start.Add(time.Hour), End: start.Add(4 * time.Hour)},
+	}}
+	if _, err := groups.SetAvailability(ctx, group.ID, creator.ID, want); err != nil {
+		t.Fatalf("SetAvailability: %v", err)
+	}
+
+	group, err = groups.GetByID(ctx, group.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	got := group.Members[0].Availability.Windows
+	expected := []AvailabilityWindow{
+		{Start: start, End: start.Add(4 * time.Hour)},
+		{Start: start.Add(24 * time.Hour), End: start.Add(27 * time.Hour)},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("windows = %+v, want %+v", got, expected)
+	}
+	for i := range expected {
+		if !got[i].Start.Equal(expected[i].Start) || !got[i].End.Equal(expected[i].End) {
+			t.Errorf("window %d = %+v, want %+v", i, got[i], expected[i])
+		}
+	}
+}
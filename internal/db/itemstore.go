@@ -0,0 +1,304 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrItemNotFound     = errors.New("item not found")
+	ErrItemTypeNotFound = errors.New("item type not found")
+	ErrInvalidItemData  = errors.New("invalid item data")
+)
+
+type ItemType struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Item is anything that can be ranked. Archived items keep their matchups
+// and ratings but are no longer offered for new comparisons.
+type Item struct {
+	ID            int        `json:"id"`
+	TypeID        int        `json:"type_id"`
+	Type          string     `json:"type"`
+	Name          string     `json:"name"`
+	CreatedBy     int        `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUpdatedAt time.Time  `json:"last_updated_at"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
+}
+
+type ItemStore struct {
+	db *sql.DB
+}
+
+func NewItemStore(db *sql.DB) *ItemStore {
+	return &ItemStore{db: db}
+}
+
+// itemColumns are the columns scanItem reads, from items i joined to
+// item_types t.
+const itemColumns = `i.id, i.type_id, t.name, i.name, COALESCE(i.created_by, 0),
+	i.created_at, i.last_updated_at, i.archived_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanItem(row scanner) (*Item, error) {
+	var item Item
+	var archived sql.NullTime
+	err := row.Scan(
+		&item.ID,
+		&item.TypeID,
+		&item.Type,
+		&item.Name,
+		&item.CreatedBy,
+		&item.CreatedAt,
+		&item.LastUpdatedAt,
+		&archived,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if archived.Valid {
+		item.ArchivedAt = &archived.Time
+	}
+	return &item, nil
+}
+
+// Types returns every item type, such as restaurant.
+func (s *ItemStore) Types(ctx context.Context) ([]ItemType, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name FROM item_types ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []ItemType
+	for rows.Next() {
+		var t ItemType
+		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+// Create adds an item of the named type. createdBy may be zero for items
+// that were not added by a user.
+func (s *ItemStore) Create(ctx context.Context, typeName, name string, createdBy int) (*Item, error) {
+	name = strings.TrimSpace(name)
+	if typeName == "" || name == "" {
+		return nil, ErrInvalidItemData
+	}
+
+	item, err := scanItem(s.db.QueryRowContext(
+		ctx,
+		`WITH inserted AS (
+			INSERT INTO items (type_id, name, created_by, created_at, last_updated_at)
+			SELECT id, $2, NULLIF($3, 0), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+			FROM item_types
+			WHERE name = $1
+			RETURNING *
+		)
+		SELECT `+itemColumns+`
+		FROM inserted i
+		JOIN item_types t ON t.id = i.type_id`,
+		typeName, name, createdBy,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrItemTypeNotFound
+	}
+	if err != nil {
+		if isPgForeignKeyViolation(err) {
+			return nil, ErrInvalidItemData
+		}
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// GetByID returns the item unless it has been archived.
+func (s *ItemStore) GetByID(ctx context.Context, id int) (*Item, error) {
+	item, err := scanItem(s.db.QueryRowContext(
+		ctx,
+		`SELECT `+itemColumns+`
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id
+		WHERE i.id = $1 AND i.archived_at IS NULL`,
+		id,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Update renames the item.
+func (s *ItemStore) Update(ctx context.Context, id int, name string) (*Item, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidItemData
+	}
+
+	item, err := scanItem(s.db.QueryRowContext(
+		ctx,
+		`WITH updated AS (
+			UPDATE items
+			SET name = $1, last_updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2 AND archived_at IS NULL
+			RETURNING *
+		)
+		SELECT `+itemColumns+`
+		FROM updated i
+		JOIN item_types t ON t.id = i.type_id`,
+		name, id,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Archive hides the item. Its matchups are kept, so ratings that were
+// earned against it still count.
+func (s *ItemStore) Archive(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE items
+		SET archived_at = CURRENT_TIMESTAMP, last_updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND archived_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrItemNotFound
+	}
+
+	return nil
+}
+
+// Restore brings back an archived item so it is offered for comparisons
+// again.
+func (s *ItemStore) Restore(ctx context.Context, id int) (*Item, error) {
+	item, err := scanItem(s.db.QueryRowContext(
+		ctx,
+		`WITH restored AS (
+			UPDATE items
+			SET archived_at = NULL, last_updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND archived_at IS NOT NULL
+			RETURNING *
+		)
+		SELECT `+itemColumns+`
+		FROM restored i
+		JOIN item_types t ON t.id = i.type_id`,
+		id,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// ListByType returns the items of the named type in name order.
+func (s *ItemStore) ListByType(ctx context.Context, typeName string, limit, offset int) ([]Item, error) {
+	if limit <= 0 {
+		limit = 50 // default limit
+	}
+
+	return s.list(
+		ctx,
+		`SELECT `+itemColumns+`
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id
+		WHERE t.name = $1 AND i.archived_at IS NULL
+		ORDER BY lower(i.name), i.id
+		LIMIT $2 OFFSET $3`,
+		typeName, limit, offset,
+	)
+}
+
+// Search returns up to limit items whose names contain query, ignoring
+// case, with names that start with it first. An empty typeName searches
+// every type.
+func (s *ItemStore) Search(ctx context.Context, query, typeName string, limit int) ([]Item, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrInvalidItemData
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(query))
+	return s.list(
+		ctx,
+		`SELECT `+itemColumns+`
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id
+		WHERE lower(i.name) LIKE '%' || $1 || '%'
+			AND ($2 = '' OR t.name = $2)
+			AND i.archived_at IS NULL
+		ORDER BY lower(i.name) NOT LIKE $1 || '%', lower(i.name), i.id
+		LIMIT $3`,
+		pattern, typeName, limit,
+	)
+}
+
+func (s *ItemStore) list(ctx context.Context, query string, args ...any) ([]Item, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
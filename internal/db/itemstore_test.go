@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestItemArchiveRoundTrip(t *testing.T) {
+	database := testDB(t)
+	ctx := context.Background()
+	items := NewItemStore(database)
+
+	name := fmt.Sprintf("Round Trip Diner %d", time.Now().UnixNano())
+	item, err := items.Create(ctx, "restaurant", "  "+name+"  ", 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() {
+		database.Exec(`DELETE FROM items WHERE id = $1`, item.ID)
+	})
+	if item.Name != name || item.Type != "restaurant" || item.ArchivedAt != nil {
+		t.Fatalf("created item = %+v, want %q as an unarchived restaurant", item, name)
+	}
+
+	renamed, err := items.Update(ctx, item.ID, name+" Two")
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if renamed.Name != name+" Two" {
+		t.Errorf("renamed item = %q, want %q", renamed.Name, name+" Two")
+	}
+
+	found := func() bool {
+		t.Helper()
+		results, err := items.Search(ctx, "round trip diner", "restaurant", 100)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		for _, r := range results {
+			if r.ID == item.ID {
+				return true
+			}
+		}
+		return false
+	}
+	if !found() {
+		t.Error("Search did not find the item by part of its name")
+	}
+
+	if err := items.Archive(ctx, item.ID); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if _, err := items.GetByID(ctx, item.ID); err != ErrItemNotFound {
+		t.Errorf("GetByID after Archive = %v, want ErrItemNotFound", err)
+	}
+	if found() {
+		t.Error("Search found the archived item")
+	}
+	if err := items.Archive(ctx, item.ID); err != ErrItemNotFound {
+		t.Errorf("second Archive = %v, want ErrItemNotFound", err)
+	}
+
+	restored, err := items.Restore(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.ArchivedAt != nil {
+		t.Errorf("restored item archived at %v, want nil", restored.ArchivedAt)
+	}
+	if _, err := items.GetByID(ctx, item.ID); err != nil {
+		t.Errorf("GetByID after Restore: %v", err)
+	}
+	if _, err := items.Restore(ctx, item.ID); err != ErrItemNotFound {
+		t.Errorf("Restore of an unarchived item = %v, want ErrItemNotFound", err)
+	}
+}
@@ -0,0 +1,388 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/hours"
+)
+
+var (
+	ErrMatchupNotFound = errors.New("matchup not found")
+	ErrInvalidMatchup  = errors.New("invalid matchup data")
+)
+
+type Outcome string
+
+const (
+	OutcomeWin  Outcome = "win"
+	OutcomeDraw Outcome = "draw"
+	// OutcomeSkip means the user could not compare the pair, usually because
+	// they have not been to both places.
+	OutcomeSkip Outcome = "skip"
+)
+
+type Matchup struct {
+	ID        int             `json:"id"`
+	Item1ID   int             `json:"item1_id"`
+	Item2ID   int             `json:"item2_id"`
+	Outcome   Outcome         `json:"outcome"`
+	WinnerID  int             `json:"winner_id,omitempty"`
+	UserID    int             `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Context   json.RawMessage `json:"context,omitempty"`
+}
+
+type MatchupChange string
+
+const (
+	MatchupRecorded  MatchupChange = "recorded"
+	MatchupRevised   MatchupChange = "revised"
+	MatchupWithdrawn MatchupChange = "withdrawn"
+)
+
+// MatchupListener is called after a matchup change has been committed.
+type MatchupListener func(ctx context.Context, change MatchupChange, m Matchup)
+
+type MatchupStore struct {
+	db        *sql.DB
+	listeners []MatchupListener
+}
+
+func NewMatchupStore(db *sql.DB) *MatchupStore {
+	return &MatchupStore{db: db}
+}
+
+// OnChange registers a listener, typically used to trigger a rating update.
+func (s *MatchupStore) OnChange(listener MatchupListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *MatchupStore) notify(ctx context.Context, change MatchupChange, m Matchup) {
+	for _, listener := range s.listeners {
+		listener(ctx, change, m)
+	}
+}
+
+// Record stores a user's verdict on a pair of items. The pair is stored with
+// the lower item id first so that (a, b) and (b, a) hit the same unique
+// constraint, and voting on a pair the user already judged revises the
+// existing matchup instead of failing. winnerID is only used for a win.
+func (s *MatchupStore) Record(ctx context.Context, userID, itemA, itemB int, outcome Outcome, winnerID int, details json.RawMessage) (*Matchup, MatchupChange, error) {
+	if userID <= 0 || itemA <= 0 || itemB <= 0 || itemA == itemB {
+		return nil, "", ErrInvalidMatchup
+	}
+	winner, err := validWinner(outcome, winnerID, itemA, itemB)
+	if err != nil {
+		return nil, "", err
+	}
+	item1, item2 := normalisePair(itemA, itemB)
+
+	var m Matchup
+	var inserted bool
+	err = s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO matchups (item1_id, item2_id, outcome, winner_id, user_id, created_at, context)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, $6)
+		ON CONFLICT (item1_id, item2_id, user_id) DO UPDATE
+		SET outcome = EXCLUDED.outcome,
+			winner_id = EXCLUDED.winner_id,
+			context = EXCLUDED.context,
+			created_at = EXCLUDED.created_at
+		RETURNING id, item1_id, item2_id, outcome, COALESCE(winner_id, 0), user_id, created_at, context, (xmax = 0)`,
+		item1, item2, outcome, winner, userID, nullJSON(details),
+	).Scan(
+		&m.ID,
+		&m.Item1ID,
+		&m.Item2ID,
+		&m.Outcome,
+		&m.WinnerID,
+		&m.UserID,
+		&m.CreatedAt,
+		(*[]byte)(&m.Context),
+		&inserted,
+	)
+	if err != nil {
+		if isPgForeignKeyViolation(err) {
+			return nil, "", ErrInvalidMatchup
+		}
+		return nil, "", err
+	}
+
+	change := MatchupRevised
+	if inserted {
+		change = MatchupRecorded
+	}
+	s.notify(ctx, change, m)
+	return &m, change, nil
+}
+
+// Revise changes the outcome of one of the user's matchups. A revision
+// counts as a fresh opinion, so created_at moves to now.
+func (s *MatchupStore) Revise(ctx context.Context, id, userID int, outcome Outcome, winnerID int) (*Matchup, error) {
+	existing, err := s.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	winner, err := validWinner(outcome, winnerID, existing.Item1ID, existing.Item2ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Matchup
+	err = s.db.QueryRowContext(
+		ctx,
+		`UPDATE matchups
+		SET outcome = $3, winner_id = $4, created_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, item1_id, item2_id, outcome, COALESCE(winner_id, 0), user_id, created_at, context`,
+		id, userID, outcome, winner,
+	).Scan(
+		&m.ID,
+		&m.Item1ID,
+		&m.Item2ID,
+		&m.Outcome,
+		&m.WinnerID,
+		&m.UserID,
+		&m.CreatedAt,
+		(*[]byte)(&m.Context),
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrMatchupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, MatchupRevised, m)
+	return &m, nil
+}
+
+// Withdraw deletes one of the user's matchups.
+func (s *MatchupStore) Withdraw(ctx context.Context, id, userID int) error {
+	var m Matchup
+	err := s.db.QueryRowContext(
+		ctx,
+		`DELETE FROM matchups
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, item1_id, item2_id, outcome, COALESCE(winner_id, 0), user_id, created_at, context`,
+		id, userID,
+	).Scan(
+		&m.ID,
+		&m.Item1ID,
+		&m.Item2ID,
+		&m.Outcome,
+		&m.WinnerID,
+		&m.UserID,
+		&m.CreatedAt,
+		(*[]byte)(&m.Context),
+	)
+
+	if err == sql.ErrNoRows {
+		return ErrMatchupNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.notify(ctx, MatchupWithdrawn, m)
+	return nil
+}
+
+func (s *MatchupStore) GetByID(ctx context.Context, id, userID int) (*Matchup, error) {
+	var m Matchup
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT id, item1_id, item2_id, outcome, COALESCE(winner_id, 0), user_id, created_at, context
+		FROM matchups
+		WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	).Scan(
+		&m.ID,
+		&m.Item1ID,
+		&m.Item2ID,
+		&m.Outcome,
+		&m.WinnerID,
+		&m.UserID,
+		&m.CreatedAt,
+		(*[]byte)(&m.Context),
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrMatchupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// validWinner checks that the winner fits the outcome and returns the value
+// to store in winner_id.
+func validWinner(outcome Outcome, winnerID, itemA, itemB int) (sql.NullInt64, error) {
+	switch outcome {
+	case OutcomeWin:
+		if winnerID != itemA && winnerID != itemB {
+			return sql.NullInt64{}, ErrInvalidMatchup
+		}
+		return sql.NullInt64{Int64: int64(winnerID), Valid: true}, nil
+	case OutcomeDraw, OutcomeSkip:
+		return sql.NullInt64{}, nil
+	default:
+		return sql.NullInt64{}, ErrInvalidMatchup
+	}
+}
+
+func normalisePair(a, b int) (int, int) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+func nullJSON(data json.RawMessage) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return []byte(data)
+}
+
+type PairItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Pair struct {
+	Left  PairItem `json:"left"`
+	Right PairItem `json:"right"`
+}
+
+type PairCandidate struct {
+	ItemID int
+	Name   string
+	// DistanceMeters is the distance from the user's home, or -1 if either
+	// location is unknown.
+	DistanceMeters float64
+	// Compared counts the user's matchups with the item that were not
+	// skipped, and Skipped the ones that were.
+	Compared int
+	Skipped  int
+	// OperatingHours is nil when the item's hours are unknown.
+	OperatingHours *hours.Hours
+}
+
+// PairCandidates returns up to limit items a pair could be built from,
+// along with how the user has judged each one so far. Most are the items
+// nearest to the user's home, and the rest are the items with the fewest
+// comparisons from anyone, wherever they are, so places outside the nearest
+// few still get rated. Ties are broken by id so the same seed picks the same
+// pairs.
+func (s *MatchupStore) PairCandidates(ctx context.Context, userID, limit int) ([]PairCandidate, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	leastCompared := limit / 4
+	nearest := limit - leastCompared
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, name, distance, compared, skipped, operating_hours
+		FROM (
+			SELECT *,
+				row_number() OVER (ORDER BY distance < 0, distance, id) AS nearness,
+				row_number() OVER (ORDER BY matches, id) AS scarcity
+			FROM (
+				SELECT i.id, i.name, rm.operating_hours,
+					COALESCE(earth_distance(
+						ll_to_earth(p.home_location_lat, p.home_location_lon),
+						ll_to_earth(rm.latitude, rm.longitude)
+					), -1) AS distance,
+					COALESCE((SELECT r.matches FROM ratings r WHERE r.item_id = i.id), 0) AS matches,
+					COUNT(m.id) FILTER (WHERE m.outcome <> 'skip') AS compared,
+					COUNT(m.id) FILTER (WHERE m.outcome = 'skip') AS skipped
+				FROM items i
+				JOIN item_types t ON t.id = i.type_id AND t.name = 'restaurant'
+				LEFT JOIN restaurant_metadata rm ON rm.item_id = i.id
+				LEFT JOIN user_profiles p ON p.user_id = $1
+				LEFT JOIN matchups m ON m.user_id = $1 AND (m.item1_id = i.id OR m.item2_id = i.id)
+				WHERE i.archived_at IS NULL
+				GROUP BY i.id, i.name, p.home_location_lat, p.home_location_lon, rm.latitude, rm.longitude,
+					rm.operating_hours
+			) scored
+		) candidates
+		WHERE nearness <= $2 OR scarcity <= $3
+		ORDER BY nearness
+		LIMIT $4`,
+		userID, nearest, leastCompared, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []PairCandidate
+	for rows.Next() {
+		var c PairCandidate
+		var operatingHours []byte
+		err := rows.Scan(
+			&c.ItemID,
+			&c.Name,
+			&c.DistanceMeters,
+			&c.Compared,
+			&c.Skipped,
+			&operatingHours,
+		)
+		if err != nil {
+			return nil, err
+		}
+		// Invalid hours are treated as unknown so one bad row does not stop
+		// the feed, but are logged so they can be fixed.
+		if operatingHours != nil {
+			if c.OperatingHours, err = hours.Parse(operatingHours); err != nil {
+				log.Printf("item %d has invalid operating hours: %v", c.ItemID, err)
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// JudgedPairs returns every pair the user has already compared, keyed by
+// item ids in stored order.
+func (s *MatchupStore) JudgedPairs(ctx context.Context, userID int) (map[[2]int]bool, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item1_id, item2_id FROM matchups WHERE user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	judged := make(map[[2]int]bool)
+	for rows.Next() {
+		var item1, item2 int
+		if err := rows.Scan(&item1, &item2); err != nil {
+			return nil, err
+		}
+		judged[[2]int{item1, item2}] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return judged, nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE rating_snapshot_items;
+DROP TABLE rating_snapshots;
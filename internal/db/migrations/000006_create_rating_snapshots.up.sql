@@ -0,0 +1,20 @@
+CREATE TABLE rating_snapshots (
+    id SERIAL PRIMARY KEY,
+    method VARCHAR(50) NOT NULL,
+    window_start TIMESTAMP,
+    window_end TIMESTAMP,
+    user_id INTEGER REFERENCES users(id),
+    matchup_count INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE rating_snapshot_items (
+    snapshot_id INTEGER REFERENCES rating_snapshots(id) ON DELETE CASCADE,
+    item_id INTEGER REFERENCES items(id),
+    rating DOUBLE PRECISION NOT NULL,
+    std_error DOUBLE PRECISION NOT NULL,
+    matches INTEGER NOT NULL,
+    PRIMARY KEY (snapshot_id, item_id)
+);
+
+CREATE INDEX idx_rating_snapshots_method ON rating_snapshots(method, created_at);
@@ -0,0 +1,3 @@
+DELETE FROM matchups WHERE outcome <> 'win';
+ALTER TABLE matchups DROP CONSTRAINT matchups_winner_matches_outcome;
+ALTER TABLE matchups DROP COLUMN outcome;
@@ -0,0 +1,9 @@
+ALTER TABLE matchups
+    ADD COLUMN outcome VARCHAR(20) NOT NULL DEFAULT 'win'
+    CHECK (outcome IN ('win', 'draw', 'skip'));
+
+UPDATE matchups SET outcome = 'skip' WHERE winner_id IS NULL;
+
+ALTER TABLE matchups
+    ADD CONSTRAINT matchups_winner_matches_outcome
+    CHECK ((outcome = 'win') = (winner_id IS NOT NULL));
@@ -0,0 +1,9 @@
+CREATE TABLE personal_ratings (
+    user_id INTEGER REFERENCES users(id),
+    item_id INTEGER REFERENCES items(id),
+    rating DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL,
+    own_matches INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, item_id)
+);
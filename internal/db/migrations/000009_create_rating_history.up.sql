@@ -0,0 +1,10 @@
+CREATE TABLE rating_history (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER REFERENCES items(id),
+    rating DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL,
+    matches INTEGER NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_rating_history_item ON rating_history(item_id, recorded_at);
@@ -0,0 +1,9 @@
+CREATE TABLE ratings (
+    item_id INTEGER PRIMARY KEY REFERENCES items(id),
+    method VARCHAR(50) NOT NULL,
+    rating DOUBLE PRECISION NOT NULL,
+    deviation DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL DEFAULT 0,
+    matches INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
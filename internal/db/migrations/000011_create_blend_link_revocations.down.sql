@@ -0,0 +1 @@
+DROP TABLE blend_link_revocations;
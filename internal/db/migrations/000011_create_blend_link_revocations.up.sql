@@ -0,0 +1,8 @@
+CREATE TABLE blend_link_revocations (
+    link_id VARCHAR(32) PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_blend_link_revocations_expiry ON blend_link_revocations(expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE groups DROP CONSTRAINT groups_status_check;
+ALTER TABLE groups ALTER COLUMN status DROP NOT NULL;
+DROP TABLE group_invites;
@@ -0,0 +1,14 @@
+CREATE TABLE group_invites (
+    group_id INTEGER REFERENCES groups(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    invited_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, email)
+);
+
+CREATE INDEX idx_group_invites_email ON group_invites(email);
+
+UPDATE groups SET status = 'planning' WHERE status IS NULL;
+ALTER TABLE groups ALTER COLUMN status SET NOT NULL;
+ALTER TABLE groups ADD CONSTRAINT groups_status_check
+    CHECK (status IN ('planning', 'decided', 'done'));
@@ -0,0 +1,2 @@
+DROP INDEX idx_items_type_name;
+ALTER TABLE items DROP COLUMN archived_at;
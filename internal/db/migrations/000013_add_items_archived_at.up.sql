@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN archived_at TIMESTAMP;
+
+CREATE INDEX idx_items_type_name ON items(type_id, lower(name)) WHERE archived_at IS NULL;
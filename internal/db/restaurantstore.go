@@ -0,0 +1,474 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/hours"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRestaurantNotFound = errors.New("restaurant not found")
+	ErrInvalidRestaurant  = errors.New("invalid restaurant data")
+	ErrChainNotFound      = errors.New("chain not found")
+)
+
+type Chain struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Website     string    `json:"website,omitempty"`
+	Description string    `json:"description,omitempty"`
+	FoundedYear int       `json:"founded_year,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Restaurant is an item of the restaurant type with its metadata and the
+// chain it belongs to, if any.
+type Restaurant struct {
+	Item
+	Chain    *Chain   `json:"chain,omitempty"`
+	Cuisines []string `json:"cuisines"`
+	// PriceRange runs from 1, cheap, to 4, expensive. Zero means unknown.
+	PriceRange     int          `json:"price_range,omitempty"`
+	Latitude       *float64     `json:"latitude,omitempty"`
+	Longitude      *float64     `json:"longitude,omitempty"`
+	Address        string       `json:"address,omitempty"`
+	OperatingHours *hours.Hours `json:"operating_hours,omitempty"`
+	Website        string       `json:"website,omitempty"`
+	Phone          string       `json:"phone,omitempty"`
+}
+
+// NewRestaurant is what is needed to add a restaurant. Chain may name an
+// existing chain by ID, or by name, in which case a chain is created if
+// none has that name yet.
+type NewRestaurant struct {
+	Name           string       `json:"name"`
+	Chain          *Chain       `json:"chain,omitempty"`
+	Cuisines       []string     `json:"cuisines"`
+	PriceRange     int          `json:"price_range,omitempty"`
+	Latitude       *float64     `json:"latitude,omitempty"`
+	Longitude      *float64     `json:"longitude,omitempty"`
+	Address        string       `json:"address,omitempty"`
+	OperatingHours *hours.Hours `json:"operating_hours,omitempty"`
+	Website        string       `json:"website,omitempty"`
+	Phone          string       `json:"phone,omitempty"`
+}
+
+// Validate checks the restaurant can be stored, tidying its text fields.
+func (r *NewRestaurant) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrInvalidRestaurant
+	}
+	if r.PriceRange < 0 || r.PriceRange > 4 {
+		return ErrInvalidRestaurant
+	}
+	// A position needs both coordinates.
+	if (r.Latitude == nil) != (r.Longitude == nil) {
+		return ErrInvalidRestaurant
+	}
+	if r.Latitude != nil && (*r.Latitude < -90 || *r.Latitude > 90) {
+		return ErrInvalidRestaurant
+	}
+	if r.Longitude != nil && (*r.Longitude < -180 || *r.Longitude > 180) {
+		return ErrInvalidRestaurant
+	}
+	if r.OperatingHours != nil {
+		if err := r.OperatingHours.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRestaurant, err)
+		}
+	}
+	if r.Chain != nil {
+		r.Chain.Name = strings.TrimSpace(r.Chain.Name)
+		if r.Chain.ID <= 0 && r.Chain.Name == "" {
+			return ErrInvalidRestaurant
+		}
+	}
+
+	cuisines := make([]string, 0, len(r.Cuisines))
+	for _, c := range r.Cuisines {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			cuisines = append(cuisines, c)
+		}
+	}
+	r.Cuisines = cuisines
+	return nil
+}
+
+type RestaurantStore struct {
+	db *sql.DB
+}
+
+func NewRestaurantStore(db *sql.DB) *RestaurantStore {
+	return &RestaurantStore{db: db}
+}
+
+// Create adds the restaurant's item, metadata and, if it is new, its chain,
+// all or none of them.
+func (s *RestaurantStore) Create(ctx context.Context, createdBy int, r NewRestaurant) (*Restaurant, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var chainID sql.NullInt64
+	if r.Chain != nil {
+		id, err := chainFor(ctx, tx, r.Chain)
+		if err != nil {
+			return nil, err
+		}
+		chainID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
+	var itemID int
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO items (type_id, name, created_by, created_at, last_updated_at)
+		SELECT id, $1, NULLIF($2, 0), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+		FROM item_types
+		WHERE name = 'restaurant'
+		RETURNING id`,
+		r.Name, createdBy,
+	).Scan(&itemID)
+	if err == sql.ErrNoRows {
+		return nil, ErrItemTypeNotFound
+	}
+	if err != nil {
+		if isPgForeignKeyViolation(err) {
+			return nil, ErrInvalidRestaurant
+		}
+		return nil, err
+	}
+
+	var operatingHours []byte
+	if r.OperatingHours != nil {
+		if operatingHours, err = json.Marshal(r.OperatingHours); err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO restaurant_metadata (
+			item_id, chain_id, cuisine_type, price_range, latitude, longitude,
+			address, operating_hours, website, phone
+		)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''))`,
+		itemID, chainID, pq.Array(r.Cuisines), r.PriceRange, r.Latitude, r.Longitude,
+		r.Address, operatingHours, r.Website, r.Phone,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, itemID)
+}
+
+// chainFor returns the ID of the chain, checking it exists when it is given
+// by ID and creating it when a chain of that name does not exist.
+func chainFor(ctx context.Context, tx *sql.Tx, chain *Chain) (int, error) {
+	var id int
+	if chain.ID > 0 {
+		err := tx.QueryRowContext(ctx, `SELECT id FROM restaurant_chains WHERE id = $1`, chain.ID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return 0, ErrChainNotFound
+		}
+		return id, err
+	}
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT id FROM restaurant_chains WHERE lower(name) = lower($1) ORDER BY id LIMIT 1`,
+		chain.Name,
+	).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO restaurant_chains (name, website, description, founded_year, created_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, 0), CURRENT_TIMESTAMP)
+		RETURNING id`,
+		chain.Name, chain.Website, chain.Description, chain.FoundedYear,
+	).Scan(&id)
+	return id, err
+}
+
+// GetByID returns the restaurant unless it has been archived. Restaurants
+// added before they had metadata come back with only their item fields.
+func (s *RestaurantStore) GetByID(ctx context.Context, id int) (*Restaurant, error) {
+	r, err := scanRestaurant(s.db.QueryRowContext(
+		ctx,
+		`SELECT `+restaurantColumns+`
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id AND t.name = 'restaurant'
+		LEFT JOIN restaurant_metadata rm ON rm.item_id = i.id
+		LEFT JOIN restaurant_chains c ON c.id = rm.chain_id
+		WHERE i.id = $1 AND i.archived_at IS NULL`,
+		id,
+	))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrRestaurantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetOperatingHours replaces the restaurant's opening hours. Nil clears
+// them.
+func (s *RestaurantStore) SetOperatingHours(ctx context.Context, id int, h *hours.Hours) error {
+	var raw []byte
+	if h != nil {
+		if err := h.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRestaurant, err)
+		}
+		var err error
+		if raw, err = json.Marshal(h); err != nil {
+			return err
+		}
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO restaurant_metadata (item_id, operating_hours)
+		SELECT i.id, $2
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id AND t.name = 'restaurant'
+		WHERE i.id = $1 AND i.archived_at IS NULL
+		ON CONFLICT (item_id) DO UPDATE SET operating_hours = EXCLUDED.operating_hours`,
+		id, raw,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRestaurantNotFound
+	}
+
+	return nil
+}
+
+// restaurantColumns are the columns scanRestaurant reads, from items i
+// joined to item_types t, restaurant_metadata rm and restaurant_chains c.
+const restaurantColumns = itemColumns + `,
+	rm.cuisine_type, COALESCE(rm.price_range, 0), rm.latitude, rm.longitude,
+	COALESCE(rm.address, ''), rm.operating_hours, COALESCE(rm.website, ''), COALESCE(rm.phone, ''),
+	c.id, COALESCE(c.name, ''), COALESCE(c.website, ''), COALESCE(c.description, ''),
+	COALESCE(c.founded_year, 0), c.created_at`
+
+func scanRestaurant(row scanner) (*Restaurant, error) {
+	var r Restaurant
+	var archived sql.NullTime
+	var latitude, longitude sql.NullFloat64
+	var operatingHours []byte
+	var chainID sql.NullInt64
+	var chain Chain
+	var chainCreated sql.NullTime
+	err := row.Scan(
+		&r.ID,
+		&r.TypeID,
+		&r.Type,
+		&r.Name,
+		&r.CreatedBy,
+		&r.CreatedAt,
+		&r.LastUpdatedAt,
+		&archived,
+		pq.Array(&r.Cuisines),
+		&r.PriceRange,
+		&latitude,
+		&longitude,
+		&r.Address,
+		&operatingHours,
+		&r.Website,
+		&r.Phone,
+		&chainID,
+		&chain.Name,
+		&chain.Website,
+		&chain.Description,
+		&chain.FoundedYear,
+		&chainCreated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if archived.Valid {
+		r.ArchivedAt = &archived.Time
+	}
+	if r.Cuisines == nil {
+		r.Cuisines = []string{}
+	}
+	if latitude.Valid && longitude.Valid {
+		r.Latitude = &latitude.Float64
+		r.Longitude = &longitude.Float64
+	}
+	// Hours written before they were checked may not parse, and are treated
+	// as unknown rather than failing the whole read.
+	if operatingHours != nil {
+		r.OperatingHours, _ = hours.Parse(operatingHours)
+	}
+	if chainID.Valid {
+		chain.ID = int(chainID.Int64)
+		chain.CreatedAt = chainCreated.Time
+		r.Chain = &chain
+	}
+	return &r, nil
+}
+
+// MaxNearbyRadius is the widest search Nearby allows, in meters.
+const MaxNearbyRadius = 50000
+
+// NearbyFilter narrows a Nearby search. Zero values leave a filter off.
+type NearbyFilter struct {
+	// Cuisines matches restaurants serving any of them.
+	Cuisines []string
+	MinPrice int
+	MaxPrice int
+	// OpenAt keeps only restaurants open at that time. Restaurants whose
+	// hours are not known are left out.
+	OpenAt time.Time
+	Limit  int
+}
+
+type NearbyRestaurant struct {
+	Restaurant
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+// Nearby returns the restaurants within radiusMeters of the point, nearest
+// first.
+func (s *RestaurantStore) Nearby(ctx context.Context, lat, lon float64, radiusMeters int, f NearbyFilter) ([]NearbyRestaurant, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, ErrInvalidRestaurant
+	}
+	if radiusMeters <= 0 || radiusMeters > MaxNearbyRadius {
+		return nil, ErrInvalidRestaurant
+	}
+	if f.MinPrice < 0 || f.MaxPrice < 0 || f.MinPrice > 4 || f.MaxPrice > 4 ||
+		(f.MaxPrice > 0 && f.MinPrice > f.MaxPrice) {
+		return nil, ErrInvalidRestaurant
+	}
+	if f.Limit <= 0 {
+		f.Limit = 20
+	}
+	cuisines := make([]string, 0, len(f.Cuisines))
+	for _, c := range f.Cuisines {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			cuisines = append(cuisines, c)
+		}
+	}
+
+	// Opening hours are checked here rather than in the query, so rows are
+	// read until enough open restaurants have been found.
+	var limit sql.NullInt64
+	if f.OpenAt.IsZero() {
+		limit = sql.NullInt64{Int64: int64(f.Limit), Valid: true}
+	}
+
+	// The bounding box lets the GiST index on point(longitude, latitude)
+	// rule most rows out before the exact distance is worked out.
+	box := boundingBox(lat, lon, float64(radiusMeters))
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+restaurantColumns+`,
+			earth_distance(ll_to_earth($1, $2), ll_to_earth(rm.latitude, rm.longitude)) AS distance
+		FROM items i
+		JOIN item_types t ON t.id = i.type_id AND t.name = 'restaurant'
+		JOIN restaurant_metadata rm ON rm.item_id = i.id
+		LEFT JOIN restaurant_chains c ON c.id = rm.chain_id
+		WHERE i.archived_at IS NULL
+			AND point(rm.longitude, rm.latitude) <@ box(point($4, $5), point($6, $7))
+			AND earth_distance(ll_to_earth($1, $2), ll_to_earth(rm.latitude, rm.longitude)) <= $3
+			AND (cardinality($8::varchar[]) = 0 OR rm.cuisine_type && $8::varchar[])
+			AND ($9 = 0 OR rm.price_range >= $9)
+			AND ($10 = 0 OR rm.price_range <= $10)
+		ORDER BY distance, i.id
+		LIMIT $11`,
+		lat, lon, radiusMeters,
+		box[0], box[1], box[2], box[3],
+		pq.Array(cuisines), f.MinPrice, f.MaxPrice, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	restaurants := []NearbyRestaurant{}
+	for rows.Next() && len(restaurants) < f.Limit {
+		var distance float64
+		r, err := scanRestaurant(nearbyRow{rows, &distance})
+		if err != nil {
+			return nil, err
+		}
+		if !f.OpenAt.IsZero() && !openAt(r, f.OpenAt) {
+			continue
+		}
+		restaurants = append(restaurants, NearbyRestaurant{Restaurant: *r, DistanceMeters: distance})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return restaurants, nil
+}
+
+// nearbyRow reads the distance after a restaurant's columns.
+type nearbyRow struct {
+	rows     *sql.Rows
+	distance *float64
+}
+
+func (r nearbyRow) Scan(dest ...any) error {
+	return r.rows.Scan(append(dest, r.distance)...)
+}
+
+// boundingBox returns the corners, as min lon, min lat, max lon, max lat,
+// of a box holding every point within meters of lat, lon. Near the poles or
+// across the antimeridian it widens to every longitude rather than wrap.
+func boundingBox(lat, lon, meters float64) [4]float64 {
+	const metersPerDegree = 111320
+	dLat := meters / metersPerDegree
+	minLat, maxLat := math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+
+	minLon, maxLon := -180.0, 180.0
+	if cos := math.Cos(math.Max(math.Abs(minLat), math.Abs(maxLat)) * math.Pi / 180); cos > 0.01 {
+		dLon := meters / (metersPerDegree * cos)
+		if lon-dLon >= -180 && lon+dLon <= 180 {
+			minLon, maxLon = lon-dLon, lon+dLon
+		}
+	}
+	return [4]float64{minLon, minLat, maxLon, maxLat}
+}
+
+func openAt(r *Restaurant, t time.Time) bool {
+	if r.OperatingHours == nil {
+		return false
+	}
+	open, err := r.OperatingHours.IsOpenAt(t)
+	return err == nil && open
+}
@@ -225,3 +225,8 @@ func isPgUniqueViolation(err error) bool {
 	return ok && pqErr.Code == "23505"
 }
 
+// Helper function to check for postgres foreign key violation
+func isPgForeignKeyViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23503"
+}
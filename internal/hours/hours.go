@@ -0,0 +1,280 @@
+// Package hours reads restaurants' opening hours and works out when they are
+// open.
+package hours
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	// The production image has no zoneinfo of its own.
+	_ "time/tzdata"
+)
+
+var ErrInvalidHours = errors.New("invalid operating hours")
+
+// DefaultTimeZone is used for hours that do not name a time zone.
+const DefaultTimeZone = "Europe/London"
+
+// Weekdays are the keys of Hours.Weekly, indexed by time.Weekday.
+var Weekdays = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Hours is the shape of restaurant_metadata.operating_hours, for example
+//
+//	{
+//	  "time_zone": "Europe/London",
+//	  "weekly": {
+//	    "mon": [{"open": "12:00", "close": "15:00"}, {"open": "18:00", "close": "23:00"}],
+//	    "fri": [{"open": "18:00", "close": "02:00"}]
+//	  },
+//	  "exceptions": [
+//	    {"date": "2024-12-25", "closed": true},
+//	    {"date": "2024-12-31", "spans": [{"open": "18:00", "close": "03:00"}]}
+//	  ]
+//	}
+//
+// A span that closes at or before it opens runs past midnight into the next
+// day. Days that are missing are closed. An exception replaces the weekly
+// hours on its date, such as for a bank holiday.
+type Hours struct {
+	TimeZone   string            `json:"time_zone,omitempty"`
+	Weekly     map[string][]Span `json:"weekly"`
+	Exceptions []Exception       `json:"exceptions,omitempty"`
+}
+
+// Exception is the opening hours for one date, written as YYYY-MM-DD in the
+// hours' time zone.
+type Exception struct {
+	Date   string `json:"date"`
+	Closed bool   `json:"closed,omitempty"`
+	Spans  []Span `json:"spans,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Span is one opening on a day, with times written as HH:MM.
+type Span struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// Interval is a stretch of time from Start up to, but not including, End.
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// Parse reads operating hours from JSON and checks them.
+func Parse(raw []byte) (*Hours, error) {
+	var h Hours
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHours, err)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Validate checks the time zone is known, the days are named as in
+// Weekdays, every time is HH:MM and each date has at most one exception.
+func (h *Hours) Validate() error {
+	if _, err := h.Location(); err != nil {
+		return err
+	}
+
+	for day, spans := range h.Weekly {
+		if weekday(day) < 0 {
+			return fmt.Errorf("%w: unknown day %q", ErrInvalidHours, day)
+		}
+		if err := validateSpans(spans); err != nil {
+			return err
+		}
+	}
+
+	dates := make(map[string]bool, len(h.Exceptions))
+	for _, e := range h.Exceptions {
+		if _, err := time.Parse(time.DateOnly, e.Date); err != nil {
+			return fmt.Errorf("%w: %q is not YYYY-MM-DD", ErrInvalidHours, e.Date)
+		}
+		if dates[e.Date] {
+			return fmt.Errorf("%w: more than one exception on %s", ErrInvalidHours, e.Date)
+		}
+		dates[e.Date] = true
+		if e.Closed && len(e.Spans) > 0 {
+			return fmt.Errorf("%w: %s is closed but has opening times", ErrInvalidHours, e.Date)
+		}
+		if err := validateSpans(e.Spans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSpans(spans []Span) error {
+	for _, span := range spans {
+		if _, err := clock(span.Open); err != nil {
+			return err
+		}
+		if _, err := clock(span.Close); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func weekday(name string) time.Weekday {
+	for d, n := range Weekdays {
+		if n == name {
+			return time.Weekday(d)
+		}
+	}
+	return -1
+}
+
+// spans returns the opening times for the local midnight day, from its
+// exception if it has one and the weekly hours otherwise.
+func (h *Hours) spans(day time.Time) []Span {
+	date := day.Format(time.DateOnly)
+	for _, e := range h.Exceptions {
+		if e.Date == date {
+			return e.Spans
+		}
+	}
+	return h.Weekly[Weekdays[day.Weekday()]]
+}
+
+// Location returns the time zone the hours are written in.
+func (h *Hours) Location() (*time.Location, error) {
+	name := h.TimeZone
+	if name == "" {
+		name = DefaultTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidHours, name)
+	}
+	return loc, nil
+}
+
+// Between returns the times the place is open within [from, to), in order.
+func (h *Hours) Between(from, to time.Time) ([]Interval, error) {
+	loc, err := h.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	var open []Interval
+	// Start a day early to catch spans that run past midnight into from.
+	local := from.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, span := range h.spans(day) {
+			i, err := span.on(day)
+			if err != nil {
+				return nil, err
+			}
+			if i.Start.Before(from) {
+				i.Start = from
+			}
+			if i.End.After(to) {
+				i.End = to
+			}
+			if i.Start.Before(i.End) {
+				open = append(open, i)
+			}
+		}
+	}
+	return merge(open), nil
+}
+
+// IsOpenAt reports whether the place is open at t.
+func (h *Hours) IsOpenAt(t time.Time) (bool, error) {
+	open, err := h.Between(t, t.Add(time.Nanosecond))
+	if err != nil {
+		return false, err
+	}
+	return len(open) > 0, nil
+}
+
+// nextOpeningHorizon is how far ahead NextOpening looks before deciding a
+// place is not going to open.
+const nextOpeningHorizon = 366 * 24 * time.Hour
+
+// NextOpening returns the first time at or after t that the place is open,
+// which is t itself if it is open then. ok is false if it does not open in
+// the next year.
+func (h *Hours) NextOpening(t time.Time) (next time.Time, ok bool, err error) {
+	const week = 7 * 24 * time.Hour
+	for from := t; from.Before(t.Add(nextOpeningHorizon)); from = from.Add(week) {
+		open, err := h.Between(from, from.Add(week))
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if len(open) > 0 {
+			return open[0].Start, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// on returns the span as it falls on the given local midnight.
+func (s Span) on(day time.Time) (Interval, error) {
+	opening, err := clock(s.Open)
+	if err != nil {
+		return Interval{}, err
+	}
+	closing, err := clock(s.Close)
+	if err != nil {
+		return Interval{}, err
+	}
+	if closing <= opening {
+		closing += 24 * time.Hour
+	}
+	return Interval{Start: at(day, opening), End: at(day, closing)}, nil
+}
+
+// at is the wall clock time offset after midnight on day, which is not
+// always midnight plus offset on days the clocks change.
+func at(day time.Time, offset time.Duration) time.Time {
+	minutes := int(offset / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// clock parses HH:MM into the time since midnight. 24:00 is allowed as a
+// closing time.
+func clock(s string) (time.Duration, error) {
+	var h, m int
+	if n, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("%w: %q is not HH:MM", ErrInvalidHours, s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%w: %q is not a time of day", ErrInvalidHours, s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// merge sorts intervals and joins the ones that overlap or touch.
+func merge(intervals []Interval) []Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	merged := []Interval{intervals[0]}
+	for _, i := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if i.Start.After(last.End) {
+			merged = append(merged, i)
+		} else if i.End.After(last.End) {
+			last.End = i.End
+		}
+	}
+	return merged
+}
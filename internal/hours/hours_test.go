@@ -0,0 +1,239 @@
+package hours
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func london(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestIsOpenAt(t *testing.T) {
+	loc := london(t)
+	at := func(date string, clock string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	lateFriday := &Hours{Weekly: map[string][]Span{
+		"fri": {{Open: "18:00", Close: "02:00"}},
+	}}
+	christmas := &Hours{
+		Weekly: map[string][]Span{
+			"wed": {{Open: "12:00", Close: "22:00"}},
+		},
+		Exceptions: []Exception{{Date: "2024-12-25", Closed: true}},
+	}
+	allDay := &Hours{Weekly: map[string][]Span{
+		"mon": {{Open: "00:00", Close: "00:00"}},
+	}}
+
+	tests := []struct {
+		name  string
+		hours *Hours
+		at    time.Time
+		want  bool
+	}{
+		{"friday evening", lateFriday, at("2024-06-07", "21:00"), true},
+		{"after midnight into saturday", lateFriday, at("2024-06-08", "01:30"), true},
+		{"saturday after closing", lateFriday, at("2024-06-08", "02:00"), false},
+		{"friday before opening", lateFriday, at("2024-06-07", "17:59"), false},
+		{"saturday evening", lateFriday, at("2024-06-08", "21:00"), false},
+		{"closed exception on an open day", christmas, at("2024-12-25", "13:00"), false},
+		{"the same weekday without an exception", christmas, at("2024-12-18", "13:00"), true},
+		{"open 24h at midnight", allDay, at("2024-06-10", "00:00"), true},
+		{"open 24h late on", allDay, at("2024-06-10", "23:59"), true},
+		{"open 24h ends with the day", allDay, at("2024-06-11", "00:00"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.hours.IsOpenAt(tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsOpenAt(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBetweenOnClockChanges(t *testing.T) {
+	loc := london(t)
+	h := &Hours{Weekly: map[string][]Span{
+		"sun": {{Open: "00:00", Close: "03:00"}},
+	}}
+
+	tests := []struct {
+		name  string
+		day   time.Time
+		start string
+		end   string
+		// open is the real time the place is open for, which differs from
+		// the three hours on the wall clock.
+		open time.Duration
+	}{
+		{
+			name:  "spring forward",
+			day:   time.Date(2024, 3, 31, 0, 0, 0, 0, loc),
+			start: "2024-03-31T00:00:00Z",
+			end:   "2024-03-31T02:00:00Z",
+			open:  2 * time.Hour,
+		},
+		{
+			name:  "fall back",
+			day:   time.Date(2024, 10, 27, 0, 0, 0, 0, loc),
+			start: "2024-10-26T23:00:00Z",
+			end:   "2024-10-27T03:00:00Z",
+			open:  4 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, err := h.Between(tt.day, tt.day.AddDate(0, 0, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(open) != 1 {
+				t.Fatalf("Between = %v, want one interval", open)
+			}
+			if got := open[0].Start.UTC().Format(time.RFC3339); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := open[0].End.UTC().Format(time.RFC3339); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+			if got := open[0].Duration(); got != tt.open {
+				t.Errorf("open for %v, want %v", got, tt.open)
+			}
+		})
+	}
+}
+
+func TestNextOpening(t *testing.T) {
+	loc := london(t)
+	friday := time.Date(2024, 6, 7, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		name   string
+		hours  *Hours
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name: "later the same day",
+			hours: &Hours{Weekly: map[string][]Span{
+				"fri": {{Open: "18:00", Close: "02:00"}},
+			}},
+			want:   time.Date(2024, 6, 7, 18, 0, 0, 0, loc),
+			wantOK: true,
+		},
+		{
+			name: "open now",
+			hours: &Hours{Weekly: map[string][]Span{
+				"fri": {{Open: "11:00", Close: "15:00"}},
+			}},
+			want:   friday,
+			wantOK: true,
+		},
+		{
+			name: "next week",
+			hours: &Hours{Weekly: map[string][]Span{
+				"thu": {{Open: "09:00", Close: "10:00"}},
+			}},
+			want:   time.Date(2024, 6, 13, 9, 0, 0, 0, loc),
+			wantOK: true,
+		},
+		{
+			name:   "no weekly hours",
+			hours:  &Hours{Weekly: map[string][]Span{}},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok, err := tt.hours.NextOpening(friday)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !next.Equal(tt.want) {
+				t.Errorf("NextOpening = %v, want %v", next, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hours   Hours
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			hours: Hours{
+				Weekly:     map[string][]Span{"mon": {{Open: "07:00", Close: "24:00"}}},
+				Exceptions: []Exception{{Date: "2024-12-25", Closed: true}},
+			},
+		},
+		{
+			name:    "hour past 24",
+			hours:   Hours{Weekly: map[string][]Span{"mon": {{Open: "09:00", Close: "25:00"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "single digit hour",
+			hours:   Hours{Weekly: map[string][]Span{"mon": {{Open: "7:00", Close: "12:00"}}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate exception dates",
+			hours: Hours{
+				Weekly: map[string][]Span{},
+				Exceptions: []Exception{
+					{Date: "2024-12-25", Closed: true},
+					{Date: "2024-12-25", Spans: []Span{{Open: "10:00", Close: "14:00"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown day",
+			hours:   Hours{Weekly: map[string][]Span{"monday": {{Open: "09:00", Close: "17:00"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown time zone",
+			hours:   Hours{TimeZone: "Europe/Atlantis", Weekly: map[string][]Span{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hours.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidHours) {
+				t.Errorf("Validate() = %v, want ErrInvalidHours", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Package leaderboard ranks items by rating for display.
+package leaderboard
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	// trendWindow is how far back rank changes are measured from.
+	trendWindow = 7 * 24 * time.Hour
+	// trendRefresh is how long the rankings from a week ago are reused
+	// before being replayed again.
+	trendRefresh = time.Hour
+	// maxCachedTrends bounds how many views the rankings from a week ago are
+	// kept for, since the views come from the query string.
+	maxCachedTrends = 64
+)
+
+type Entry struct {
+	Rank        int     `json:"rank"`
+	ItemID      int     `json:"item_id"`
+	Name        string  `json:"name"`
+	Rating      float64 `json:"rating"`
+	Deviation   float64 `json:"deviation"`
+	Confidence  float64 `json:"confidence"`
+	Provisional bool    `json:"provisional"`
+	Comparisons int     `json:"comparisons"`
+	// RankChange is how many places the item has climbed over the last week.
+	// It is zero for items that were not ranked a week ago.
+	RankChange int  `json:"rank_change"`
+	New        bool `json:"new"`
+}
+
+type Board struct {
+	Entries []Entry `json:"entries"`
+	Page    int     `json:"page"`
+	PerPage int     `json:"per_page"`
+	Total   int     `json:"total"`
+	Segment string  `json:"segment,omitempty"`
+	// HalfLife is set on boards where older comparisons count for less,
+	// in the form accepted by rating.ParseHalfLife.
+	HalfLife string `json:"half_life,omitempty"`
+}
+
+func (b *Board) Pages() int {
+	if b.PerPage <= 0 {
+		return 0
+	}
+	return (b.Total + b.PerPage - 1) / b.PerPage
+}
+
+type Options struct {
+	// Page is 1-based.
+	Page    int
+	PerPage int
+	// Segment ranks items using only matchups from matching users.
+	Segment rating.Segment
+	// HalfLife, when set, ranks recent performance by halving the weight of
+	// a comparison every HalfLife. Zero ranks all time.
+	HalfLife time.Duration
+}
+
+type Service struct {
+	db       *sql.DB
+	engine   *rating.Engine
+	newRater func() rating.Rater
+
+	mu       sync.Mutex
+	lastWeek map[string]weekRanks
+}
+
+type weekRanks struct {
+	ranks    map[int]int
+	computed time.Time
+}
+
+func NewService(db *sql.DB, engine *rating.Engine, newRater func() rating.Rater) *Service {
+	return &Service{db: db, engine: engine, newRater: newRater, lastWeek: make(map[string]weekRanks)}
+}
+
+func (s *Service) Board(ctx context.Context, opts Options) (*Board, error) {
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PerPage <= 0 {
+		opts.PerPage = defaultPerPage
+	}
+	if opts.PerPage > maxPerPage {
+		opts.PerPage = maxPerPage
+	}
+
+	view := rating.View{Segment: opts.Segment, HalfLife: opts.HalfLife}
+	current, err := s.engine.ViewRatings(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.lastWeekRanks(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := rating.Ranked(current)
+	board := &Board{
+		Page:     opts.Page,
+		PerPage:  opts.PerPage,
+		Total:    len(ranked),
+		Segment:  opts.Segment.String(),
+		HalfLife: rating.FormatHalfLife(opts.HalfLife),
+	}
+
+	start := (opts.Page - 1) * opts.PerPage
+	if start >= len(ranked) {
+		return board, nil
+	}
+	end := min(start+opts.PerPage, len(ranked))
+
+	for i, r := range ranked[start:end] {
+		e := Entry{
+			Rank:        start + i + 1,
+			ItemID:      r.ItemID,
+			Rating:      r.Rating,
+			Deviation:   r.Deviation,
+			Confidence:  confidence(r),
+			Provisional: r.Provisional(),
+			Comparisons: r.Matches,
+		}
+		if prev, ok := previous[r.ItemID]; ok {
+			e.RankChange = prev - e.Rank
+		} else {
+			e.New = true
+		}
+		board.Entries = append(board.Entries, e)
+	}
+
+	if err := s.addNames(ctx, board.Entries); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// Personal returns the user's highest personal ratings. It is empty until
+// the user has compared something.
+func (s *Service) Personal(ctx context.Context, userID, limit int) ([]Entry, error) {
+	ratings, err := rating.NewStore(s.db).PersonalTop(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(ratings))
+	for i, r := range ratings {
+		entries[i] = Entry{
+			Rank:        i + 1,
+			ItemID:      r.ItemID,
+			Rating:      r.Rating,
+			Deviation:   r.Deviation,
+			Confidence:  confidence(r),
+			Provisional: r.Provisional(),
+			Comparisons: r.Matches,
+		}
+	}
+
+	if err := s.addNames(ctx, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// lastWeekRanks replays the view's matchups made before a week ago, decayed
+// as they would have been then, and returns the rank each item held.
+func (s *Service) lastWeekRanks(ctx context.Context, view rating.View) (map[int]int, error) {
+	key := view.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.lastWeek[key]; ok && time.Since(cached.computed) < trendRefresh {
+		return cached.ranks, nil
+	}
+
+	now := time.Now()
+	weekAgo := now.Add(-trendWindow)
+	matchups, err := view.Load(ctx, s.db, rating.Filter{To: weekAgo}, weekAgo)
+	if err != nil {
+		return nil, err
+	}
+	r := s.newRater()
+	r.Process(matchups)
+
+	ranks := make(map[int]int)
+	for i, rt := range rating.Ranked(r.Ratings()) {
+		ranks[rt.ItemID] = i + 1
+	}
+	for k, cached := range s.lastWeek {
+		if time.Since(cached.computed) >= trendRefresh {
+			delete(s.lastWeek, k)
+		}
+	}
+	if len(s.lastWeek) >= maxCachedTrends {
+		clear(s.lastWeek)
+	}
+	s.lastWeek[key] = weekRanks{ranks: ranks, computed: now}
+	return ranks, nil
+}
+
+func (s *Service) addNames(ctx context.Context, entries []Entry) error {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = int64(e.ItemID)
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, name FROM items WHERE id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].Name = names[entries[i].ItemID]
+	}
+	return nil
+}
+
+// confidence maps a deviation onto 0 for an unrated item up to 1 for one
+// whose rating is certain.
+func confidence(r rating.Rating) float64 {
+	maxDeviation := rating.DefaultGlicko2Config().InitialDeviation
+	return math.Max(0, math.Min(1, 1-r.Deviation/maxDeviation))
+}
@@ -0,0 +1,149 @@
+// Package pairing decides which pair of items to show a user next.
+package pairing
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+type Config struct {
+	// Seed makes selection repeatable. Zero picks a random seed per call.
+	Seed uint64
+	// NearbyMeters is the distance from the user's home at which an item's
+	// proximity bonus has dropped to about a third.
+	NearbyMeters float64
+	// MaxDeviation is the deviation of an item nobody has rated yet.
+	MaxDeviation float64
+	// Jitter is the largest random bonus added to a pair's score so that
+	// near-identical pairs are not always offered in the same order.
+	Jitter float64
+	// SkipLimit is how many skips of an item, with no real comparisons, it
+	// takes to conclude the user has never been there and stop offering it.
+	SkipLimit int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		NearbyMeters: 5000,
+		MaxDeviation: 350,
+		Jitter:       0.1,
+		SkipLimit:    2,
+	}
+}
+
+// Candidate is an item that could be shown to the user, with what we know
+// about it from the user's point of view.
+type Candidate struct {
+	ItemID    int
+	Name      string
+	Rating    float64
+	Deviation float64
+	// DistanceMeters is negative when the distance is unknown.
+	DistanceMeters float64
+	// Compared is how many matchups the user has made involving the item,
+	// not counting the ones they skipped.
+	Compared int
+	// Skipped is how many pairs with the item the user could not compare.
+	Skipped int
+}
+
+type Pair struct {
+	Left  Candidate
+	Right Candidate
+	Score float64
+}
+
+type Selector struct {
+	cfg Config
+	rng *rand.Rand
+}
+
+func NewSelector(cfg Config, seed uint64) *Selector {
+	return &Selector{cfg: cfg, rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// Select returns up to n pairs ordered by expected information gain. Pairs in
+// judged, keyed with the lower item id first, are never returned and no item
+// appears in more than one pair, so a feed built from the result does not
+// repeat itself.
+func (s *Selector) Select(candidates []Candidate, judged map[[2]int]bool, n int) []Pair {
+	// Sort first so the random jitter is applied in the same order for the
+	// same input, whatever order the caller built it in.
+	sorted := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if s.unvisited(c) {
+			continue
+		}
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ItemID < sorted[j].ItemID })
+
+	var pairs []Pair
+	for i := 0; i < len(sorted); i++ {
+		for j := i + 1; j < len(sorted); j++ {
+			a, b := sorted[i], sorted[j]
+			if a.ItemID == b.ItemID || judged[[2]int{a.ItemID, b.ItemID}] {
+				continue
+			}
+			if s.rng.IntN(2) == 0 {
+				a, b = b, a
+			}
+			pairs = append(pairs, Pair{Left: a, Right: b, Score: s.score(a, b)})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+
+	used := make(map[int]bool)
+	var selected []Pair
+	for _, p := range pairs {
+		if len(selected) == n {
+			break
+		}
+		if used[p.Left.ItemID] || used[p.Right.ItemID] {
+			continue
+		}
+		used[p.Left.ItemID] = true
+		used[p.Right.ItemID] = true
+		selected = append(selected, p)
+	}
+	return selected
+}
+
+// score favours pairs whose outcome is hardest to predict, whose ratings are
+// least certain, that are close to the user, that the user has not seen
+// much of and that the user has not had to skip.
+func (s *Selector) score(a, b Candidate) float64 {
+	p := rating.ExpectedScore(a.Rating, b.Rating)
+	closeness := 4 * p * (1 - p)
+
+	maxVariance := 2 * s.cfg.MaxDeviation * s.cfg.MaxDeviation
+	uncertainty := math.Min((a.Deviation*a.Deviation+b.Deviation*b.Deviation)/maxVariance, 1)
+
+	proximity := (s.proximity(a) + s.proximity(b)) / 2
+	novelty := (novelty(a) + novelty(b)) / 2
+	familiarity := 1 / float64(1+a.Skipped+b.Skipped)
+
+	return closeness * (1 + uncertainty) * (0.5 + proximity) * (0.5 + novelty) * familiarity *
+		(1 + s.cfg.Jitter*s.rng.Float64())
+}
+
+// unvisited reports whether the user keeps skipping pairs with c and has
+// never managed to compare it.
+func (s *Selector) unvisited(c Candidate) bool {
+	return s.cfg.SkipLimit > 0 && c.Compared == 0 && c.Skipped >= s.cfg.SkipLimit
+}
+
+func (s *Selector) proximity(c Candidate) float64 {
+	if c.DistanceMeters < 0 || s.cfg.NearbyMeters <= 0 {
+		return 0.5
+	}
+	return math.Exp(-c.DistanceMeters / s.cfg.NearbyMeters)
+}
+
+func novelty(c Candidate) float64 {
+	return 1 / float64(1+c.Compared)
+}
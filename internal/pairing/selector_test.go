@@ -0,0 +1,124 @@
+package pairing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func candidates(ids ...int) []Candidate {
+	cs := make([]Candidate, 0, len(ids))
+	for _, id := range ids {
+		cs = append(cs, Candidate{
+			ItemID:         id,
+			Rating:         1500,
+			Deviation:      350,
+			DistanceMeters: -1,
+		})
+	}
+	return cs
+}
+
+func pairKey(p Pair) [2]int {
+	a, b := p.Left.ItemID, p.Right.ItemID
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+func TestSelect(t *testing.T) {
+	skipped := candidates(1, 2, 3, 4)
+	skipped[3].Skipped = 2
+
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		judged     map[[2]int]bool
+		n          int
+		// excluded are items that must not appear in any pair.
+		excluded []int
+		want     int
+	}{
+		{
+			name:       "judged pairs are excluded",
+			candidates: candidates(1, 2, 3),
+			judged:     map[[2]int]bool{{1, 2}: true, {1, 3}: true},
+			n:          3,
+			want:       1,
+		},
+		{
+			name:       "no item repeats within a batch",
+			candidates: candidates(1, 2, 3, 4, 5, 6, 7),
+			n:          10,
+			want:       3,
+		},
+		{
+			name:       "items past the skip limit are excluded",
+			candidates: skipped,
+			n:          3,
+			excluded:   []int{4},
+			want:       1,
+		},
+		{
+			name:       "skipped items the user has compared are kept",
+			candidates: append(candidates(1), Candidate{ItemID: 2, Compared: 1, Skipped: 5, Deviation: 350}),
+			n:          1,
+			want:       1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := NewSelector(DefaultConfig(), 1).Select(tt.candidates, tt.judged, tt.n)
+			if len(pairs) != tt.want {
+				t.Fatalf("got %d pairs, want %d: %+v", len(pairs), tt.want, pairs)
+			}
+
+			seen := make(map[int]bool)
+			for _, p := range pairs {
+				if tt.judged[pairKey(p)] {
+					t.Errorf("pair %v was already judged", pairKey(p))
+				}
+				for _, id := range []int{p.Left.ItemID, p.Right.ItemID} {
+					if seen[id] {
+						t.Errorf("item %d appears in more than one pair", id)
+					}
+					seen[id] = true
+				}
+			}
+			for _, id := range tt.excluded {
+				if seen[id] {
+					t.Errorf("item %d should have been excluded", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectSameSeed(t *testing.T) {
+	cs := candidates(1, 2, 3, 4, 5, 6, 7, 8)
+	cs[2].Rating = 1700
+	cs[5].DistanceMeters = 1200
+	reversed := make([]Candidate, len(cs))
+	for i, c := range cs {
+		reversed[len(cs)-1-i] = c
+	}
+
+	tests := []struct {
+		name string
+		a, b []Candidate
+	}{
+		{name: "same input", a: cs, b: cs},
+		{name: "same input in another order", a: cs, b: reversed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSelector(DefaultConfig(), 42).Select(tt.a, nil, 4)
+			b := NewSelector(DefaultConfig(), 42).Select(tt.b, nil, 4)
+			if len(a) == 0 || !reflect.DeepEqual(a, b) {
+				t.Errorf("same seed gave different pairs:\n%+v\n%+v", a, b)
+			}
+		})
+	}
+}
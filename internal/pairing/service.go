@@ -0,0 +1,93 @@
+package pairing
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/hours"
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+// candidatePool caps how many items are scored against each other per call.
+const candidatePool = 200
+
+type RatingSource interface {
+	Ratings(ctx context.Context) (map[int]rating.Rating, error)
+}
+
+type Service struct {
+	cfg      Config
+	matchups *db.MatchupStore
+	ratings  RatingSource
+}
+
+func NewService(cfg Config, matchups *db.MatchupStore, ratings RatingSource) *Service {
+	return &Service{cfg: cfg, matchups: matchups, ratings: ratings}
+}
+
+// Next returns up to n pairs for the user to compare, best first. Places
+// whose hours show they will not open again are left out, since they can no
+// longer be visited.
+func (s *Service) Next(ctx context.Context, userID, n int) ([]db.Pair, error) {
+	items, err := s.matchups.PairCandidates(ctx, userID, candidatePool)
+	if err != nil {
+		return nil, err
+	}
+	judged, err := s.matchups.JudgedPairs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := s.ratings.Ratings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	candidates := make([]Candidate, 0, len(items))
+	for _, it := range items {
+		if closedFor(it.OperatingHours, now) {
+			continue
+		}
+		c := Candidate{
+			ItemID:         it.ItemID,
+			Name:           it.Name,
+			Rating:         1500,
+			Deviation:      s.cfg.MaxDeviation,
+			DistanceMeters: it.DistanceMeters,
+			Compared:       it.Compared,
+			Skipped:        it.Skipped,
+		}
+		if r, ok := ratings[it.ItemID]; ok {
+			c.Rating = r.Rating
+			c.Deviation = r.Deviation
+		}
+		candidates = append(candidates, c)
+	}
+
+	seed := s.cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	selected := NewSelector(s.cfg, seed).Select(candidates, judged, n)
+	pairs := make([]db.Pair, len(selected))
+	for i, p := range selected {
+		pairs[i] = db.Pair{
+			Left:  db.PairItem{ID: p.Left.ItemID, Name: p.Left.Name},
+			Right: db.PairItem{ID: p.Right.ItemID, Name: p.Right.Name},
+		}
+	}
+	return pairs, nil
+}
+
+// closedFor reports whether the hours show no opening in the coming year.
+// Unknown or unreadable hours are not taken to mean the place has closed.
+func closedFor(h *hours.Hours, now time.Time) bool {
+	if h == nil {
+		return false
+	}
+	_, ok, err := h.NextOpening(now)
+	return err == nil && !ok
+}
@@ -0,0 +1,116 @@
+// Package planning helps a group decide where to go.
+package planning
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var ErrUnknownAggregation = errors.New("unknown aggregation")
+
+// Aggregation combines the members' predicted ratings of a place into one
+// score for the group.
+type Aggregation string
+
+const (
+	// Average favours the places the group likes most overall.
+	Average Aggregation = "average"
+	// LeastMisery scores a place by its least keen member, so nobody is
+	// dragged somewhere they would hate.
+	LeastMisery Aggregation = "least-misery"
+	// Borda has every member rank the places and awards points by position,
+	// so each member has an equal say however extreme their ratings are.
+	Borda Aggregation = "borda"
+)
+
+func ParseAggregation(s string) (Aggregation, error) {
+	switch a := Aggregation(s); a {
+	case "":
+		return Average, nil
+	case Average, LeastMisery, Borda:
+		return a, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownAggregation, s)
+	}
+}
+
+// aggregate scores every place for the group. ratings[m][p] is member m's
+// predicted rating of place p.
+func aggregate(ratings [][]float64, places int, agg Aggregation) []float64 {
+	scores := make([]float64, places)
+	if len(ratings) == 0 {
+		return scores
+	}
+
+	switch agg {
+	case LeastMisery:
+		for p := range scores {
+			scores[p] = math.Inf(1)
+			for _, member := range ratings {
+				scores[p] = math.Min(scores[p], member[p])
+			}
+		}
+	case Borda:
+		for _, member := range ratings {
+			for p, points := range bordaPoints(member) {
+				scores[p] += points
+			}
+		}
+	default:
+		for _, member := range ratings {
+			for p, r := range member {
+				scores[p] += r
+			}
+		}
+		for p := range scores {
+			scores[p] /= float64(len(ratings))
+		}
+	}
+	return scores
+}
+
+// bordaPoints gives the lowest rated place 0 points, the next 1, and so on.
+// Places with equal ratings share the points for the positions they span.
+func bordaPoints(ratings []float64) []float64 {
+	order := make([]int, len(ratings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ratings[order[i]] < ratings[order[j]]
+	})
+
+	points := make([]float64, len(ratings))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && ratings[order[end]] == ratings[order[start]] {
+			end++
+		}
+		shared := float64(start+end-1) / 2
+		for _, p := range order[start:end] {
+			points[p] = shared
+		}
+		start = end
+	}
+	return points
+}
+
+// ranks returns each place's position, from 1 for the highest rated, in one
+// member's ratings.
+func ranks(ratings []float64) []int {
+	order := make([]int, len(ratings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ratings[order[i]] > ratings[order[j]]
+	})
+
+	positions := make([]int, len(ratings))
+	for pos, p := range order {
+		positions[p] = pos + 1
+	}
+	return positions
+}
@@ -0,0 +1,144 @@
+package planning
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+const defaultPlaces = 10
+
+type RatingSource interface {
+	Ratings(ctx context.Context) (map[int]rating.Rating, error)
+}
+
+// MemberScore is how much one member is predicted to like a place.
+type MemberScore struct {
+	UserID int     `json:"user_id"`
+	Name   string  `json:"name"`
+	Rating float64 `json:"rating"`
+	// Rank is where the place comes in the member's own order of every
+	// candidate, from 1.
+	Rank int `json:"rank"`
+	// Personal is set when the rating comes from the member's own
+	// comparisons rather than the global rating.
+	Personal bool `json:"personal"`
+}
+
+type Place struct {
+	ItemID  int           `json:"item_id"`
+	Name    string        `json:"name"`
+	Score   float64       `json:"score"`
+	Members []MemberScore `json:"members"`
+}
+
+type Ranking struct {
+	GroupID     int         `json:"group_id"`
+	Aggregation Aggregation `json:"aggregation"`
+	// Candidates is how many places were within every member's reach.
+	Candidates int     `json:"candidates"`
+	Places     []Place `json:"places"`
+}
+
+type Service struct {
+	db      *sql.DB
+	groups  *db.GroupStore
+	ratings RatingSource
+}
+
+func NewService(database *sql.DB, groups *db.GroupStore, ratings RatingSource) *Service {
+	return &Service{db: database, groups: groups, ratings: ratings}
+}
+
+// Rank returns up to limit places within every member's reach, best for the
+// group first. Each member's prediction for a place is their personal rating
+// where they have one and the global rating otherwise.
+func (s *Service) Rank(ctx context.Context, groupID int, agg Aggregation, limit int) (*Ranking, error) {
+	if limit <= 0 {
+		limit = defaultPlaces
+	}
+
+	group, err := s.groups.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.groups.Candidates(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	global, err := s.ratings.Ratings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	initial := rating.DefaultGlicko2Config().InitialRating
+	store := rating.NewStore(s.db)
+	predictions := make([][]float64, len(group.Members))
+	personal := make([][]bool, len(group.Members))
+	for m, member := range group.Members {
+		own, err := store.Personal(ctx, member.UserID)
+		if err != nil {
+			return nil, err
+		}
+		predictions[m] = make([]float64, len(candidates))
+		personal[m] = make([]bool, len(candidates))
+		for p, c := range candidates {
+			r, ok := own[c.ItemID]
+			if !ok {
+				r, ok = global[c.ItemID]
+				if !ok {
+					r.Rating = initial
+				}
+			} else {
+				personal[m][p] = true
+			}
+			predictions[m][p] = r.Rating
+		}
+	}
+
+	scores := aggregate(predictions, len(candidates), agg)
+	memberRanks := make([][]int, len(predictions))
+	for m := range predictions {
+		memberRanks[m] = ranks(predictions[m])
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	ranking := &Ranking{
+		GroupID:     groupID,
+		Aggregation: agg,
+		Candidates:  len(candidates),
+		Places:      []Place{},
+	}
+	for _, p := range order {
+		place := Place{
+			ItemID:  candidates[p].ItemID,
+			Name:    candidates[p].Name,
+			Score:   scores[p],
+			Members: make([]MemberScore, len(group.Members)),
+		}
+		for m, member := range group.Members {
+			place.Members[m] = MemberScore{
+				UserID:   member.UserID,
+				Name:     member.Name,
+				Rating:   predictions[m][p],
+				Rank:     memberRanks[m][p],
+				Personal: personal[m][p],
+			}
+		}
+		ranking.Places = append(ranking.Places, place)
+	}
+	return ranking, nil
+}
@@ -0,0 +1,278 @@
+package planning
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/hours"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultSlots       = 5
+	defaultMinDuration = time.Hour
+	// timeHorizon is how far ahead slots are looked for, so a member who is
+	// free "forever" does not mean walking years of opening hours.
+	timeHorizon = 60 * 24 * time.Hour
+)
+
+type TimeOptions struct {
+	// From is the earliest a slot can start. It defaults to now.
+	From time.Time
+	// MinDuration is how long a slot has to be. It defaults to an hour.
+	MinDuration time.Duration
+	Limit       int
+}
+
+type Attendee struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// SlotPlace is one of the group's top places and when it is open during a
+// slot. Places whose hours are not known are listed without times.
+type SlotPlace struct {
+	ItemID     int        `json:"item_id"`
+	Name       string     `json:"name"`
+	HoursKnown bool       `json:"hours_known"`
+	OpenFrom   *time.Time `json:"open_from,omitempty"`
+	OpenUntil  *time.Time `json:"open_until,omitempty"`
+}
+
+// Slot is a stretch of time when the same members are all free.
+type Slot struct {
+	Start     time.Time   `json:"start"`
+	End       time.Time   `json:"end"`
+	Available []Attendee  `json:"available"`
+	Missing   []Attendee  `json:"missing"`
+	Places    []SlotPlace `json:"places"`
+}
+
+func (s Slot) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// open counts the places known to be open during the slot.
+func (s Slot) open() int {
+	n := 0
+	for _, p := range s.Places {
+		if p.HoursKnown {
+			n++
+		}
+	}
+	return n
+}
+
+type Times struct {
+	GroupID int    `json:"group_id"`
+	Slots   []Slot `json:"slots"`
+}
+
+// BestTimes finds when the most members are free together, and which of the
+// group's top places are open then. Slots are ordered by how many members
+// can come, then how many places are open, then length, then how soon they
+// start.
+func (s *Service) BestTimes(ctx context.Context, groupID int, opts TimeOptions) (*Times, error) {
+	if opts.From.IsZero() {
+		opts.From = time.Now()
+	}
+	if opts.MinDuration <= 0 {
+		opts.MinDuration = defaultMinDuration
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSlots
+	}
+
+	group, err := s.groups.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	ranking, err := s.Rank(ctx, groupID, Average, defaultPlaces)
+	if err != nil {
+		return nil, err
+	}
+	opening, err := s.operatingHours(ctx, ranking.Places)
+	if err != nil {
+		return nil, err
+	}
+
+	times := &Times{GroupID: groupID, Slots: []Slot{}}
+	to := opts.From.Add(timeHorizon)
+	for _, slot := range overlap(group.Members, opts.From, to, opts.MinDuration) {
+		for _, place := range ranking.Places {
+			h, ok := opening[place.ItemID]
+			if !ok {
+				slot.Places = append(slot.Places, SlotPlace{ItemID: place.ItemID, Name: place.Name})
+				continue
+			}
+			open, err := h.Between(slot.Start, slot.End)
+			if err != nil {
+				slot.Places = append(slot.Places, SlotPlace{ItemID: place.ItemID, Name: place.Name})
+				continue
+			}
+			// The longest stretch open within the slot is the one worth
+			// showing.
+			var best *hours.Interval
+			for i := range open {
+				if open[i].Duration() >= opts.MinDuration && (best == nil || open[i].Duration() > best.Duration()) {
+					best = &open[i]
+				}
+			}
+			if best != nil {
+				slot.Places = append(slot.Places, SlotPlace{
+					ItemID:     place.ItemID,
+					Name:       place.Name,
+					HoursKnown: true,
+					OpenFrom:   &best.Start,
+					OpenUntil:  &best.End,
+				})
+			}
+		}
+		times.Slots = append(times.Slots, slot)
+	}
+
+	sort.SliceStable(times.Slots, func(i, j int) bool {
+		a, b := times.Slots[i], times.Slots[j]
+		if len(a.Available) != len(b.Available) {
+			return len(a.Available) > len(b.Available)
+		}
+		if a.open() != b.open() {
+			return a.open() > b.open()
+		}
+		if len(a.Places) != len(b.Places) {
+			return len(a.Places) > len(b.Places)
+		}
+		if a.Duration() != b.Duration() {
+			return a.Duration() > b.Duration()
+		}
+		return a.Start.Before(b.Start)
+	})
+	if len(times.Slots) > opts.Limit {
+		times.Slots = times.Slots[:opts.Limit]
+	}
+	return times, nil
+}
+
+// overlap splits [from, to) wherever a member becomes free or busy, and
+// returns the stretches of at least minDuration when anyone is free. Next
+// to each other stretches with the same members free are joined.
+func overlap(members []db.GroupMember, from, to time.Time, minDuration time.Duration) []Slot {
+	type event struct {
+		at     time.Time
+		member int
+		free   bool
+	}
+
+	var events []event
+	for m, member := range members {
+		for _, w := range member.Availability.Windows {
+			start, end := w.Start, w.End
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if start.Before(end) {
+				events = append(events, event{start, m, true}, event{end, m, false})
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+
+	var slots []Slot
+	free := make([]int, len(members))
+	for i := 0; i < len(events); {
+		at := events[i].at
+		for ; i < len(events) && events[i].at.Equal(at); i++ {
+			if events[i].free {
+				free[events[i].member]++
+			} else {
+				free[events[i].member]--
+			}
+		}
+		if i == len(events) {
+			break
+		}
+
+		slot := Slot{Start: at, End: events[i].at, Available: []Attendee{}, Missing: []Attendee{}}
+		for m, member := range members {
+			attendee := Attendee{UserID: member.UserID, Name: member.Name}
+			if free[m] > 0 {
+				slot.Available = append(slot.Available, attendee)
+			} else {
+				slot.Missing = append(slot.Missing, attendee)
+			}
+		}
+		if len(slot.Available) == 0 {
+			continue
+		}
+		if n := len(slots); n > 0 && slots[n-1].End.Equal(slot.Start) && sameAttendees(slots[n-1].Available, slot.Available) {
+			slots[n-1].End = slot.End
+			continue
+		}
+		slots = append(slots, slot)
+	}
+
+	long := slots[:0]
+	for _, slot := range slots {
+		if slot.Duration() >= minDuration {
+			slot.Places = []SlotPlace{}
+			long = append(long, slot)
+		}
+	}
+	return long
+}
+
+func sameAttendees(a, b []Attendee) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].UserID != b[i].UserID {
+			return false
+		}
+	}
+	return true
+}
+
+// operatingHours returns the opening hours of the places that have them.
+// Hours that cannot be read are left out, as if they were not known.
+func (s *Service) operatingHours(ctx context.Context, places []Place) (map[int]*hours.Hours, error) {
+	ids := make([]int64, len(places))
+	for i, p := range places {
+		ids[i] = int64(p.ItemID)
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item_id, operating_hours
+		FROM restaurant_metadata
+		WHERE item_id = ANY($1) AND operating_hours IS NOT NULL`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	opening := make(map[int]*hours.Hours)
+	for rows.Next() {
+		var id int
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, err
+		}
+		if h, err := hours.Parse(raw); err == nil {
+			opening[id] = h
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return opening, nil
+}
@@ -0,0 +1,87 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBradleyTerry(t *testing.T) {
+	start := time.Unix(0, 0)
+	var matchups []Matchup
+	add := func(winner, loser, times int) {
+		for range times {
+			matchups = append(matchups, Matchup{
+				ID:        len(matchups) + 1,
+				Item1ID:   winner,
+				Item2ID:   loser,
+				WinnerID:  winner,
+				CreatedAt: start.Add(time.Duration(len(matchups)) * time.Hour),
+			})
+		}
+	}
+	// 1 usually beats 2 and 3, and 2 usually beats 3.
+	add(1, 2, 3)
+	add(2, 1, 1)
+	add(2, 3, 3)
+	add(3, 2, 1)
+	add(1, 3, 4)
+
+	cfg := DefaultBradleyTerryConfig()
+	bt := NewBradleyTerry(cfg)
+	bt.Process(matchups)
+	ratings := bt.Ratings()
+
+	if !(ratings[1].Rating > ratings[2].Rating && ratings[2].Rating > ratings[3].Rating) {
+		t.Fatalf("ratings = %v, %v, %v, want 1 > 2 > 3", ratings[1].Rating, ratings[2].Rating, ratings[3].Rating)
+	}
+
+	// At the fit, each item's wins plus its prior wins equal the wins the
+	// strengths predict for it.
+	strength := func(id int) float64 {
+		return math.Exp((ratings[id].Rating - 1500) * math.Ln10 / 400)
+	}
+	games := map[[2]int]float64{{1, 2}: 4, {2, 3}: 4, {1, 3}: 4}
+	wins := map[int]float64{1: 7, 2: 4, 3: 1}
+	for id := 1; id <= 3; id++ {
+		p := strength(id)
+		expected := 2 * cfg.Prior * p / (p + 1)
+		for pair, n := range games {
+			switch id {
+			case pair[0]:
+				expected += n * p / (p + strength(pair[1]))
+			case pair[1]:
+				expected += n * p / (p + strength(pair[0]))
+			}
+		}
+		if got := wins[id] + cfg.Prior; math.Abs(got-expected) > 1e-3 {
+			t.Errorf("item %d: wins %v, fitted strengths predict %v", id, got, expected)
+		}
+		if ratings[id].Matches != 8 {
+			t.Errorf("item %d matches = %d, want 8", id, ratings[id].Matches)
+		}
+		if ratings[id].Deviation <= 0 {
+			t.Errorf("item %d deviation = %v, want positive", id, ratings[id].Deviation)
+		}
+	}
+}
+
+func TestBradleyTerryUnbeaten(t *testing.T) {
+	// Without the prior an item that never lost would have infinite
+	// strength.
+	bt := NewBradleyTerry(DefaultBradleyTerryConfig())
+	bt.Process([]Matchup{
+		{ID: 1, Item1ID: 1, Item2ID: 2, WinnerID: 1},
+		{ID: 2, Item1ID: 1, Item2ID: 2, WinnerID: 1},
+	})
+	ratings := bt.Ratings()
+
+	for id, r := range ratings {
+		if math.IsInf(r.Rating, 0) || math.IsNaN(r.Rating) {
+			t.Errorf("item %d rating = %v, want finite", id, r.Rating)
+		}
+	}
+	if ratings[1].Rating <= 1500 || ratings[2].Rating >= 1500 {
+		t.Errorf("ratings = %v and %v, want the winner above 1500 and the loser below", ratings[1].Rating, ratings[2].Rating)
+	}
+}
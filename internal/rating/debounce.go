@@ -0,0 +1,42 @@
+package rating
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer runs a function once, delay after it is first triggered, however
+// many more times it is triggered in the meantime. Runs never overlap.
+type debouncer struct {
+	delay time.Duration
+	run   func()
+
+	mu        sync.Mutex
+	scheduled bool
+	running   sync.Mutex
+}
+
+func newDebouncer(delay time.Duration, run func()) *debouncer {
+	return &debouncer{delay: delay, run: run}
+}
+
+func (d *debouncer) trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.scheduled {
+		return
+	}
+	d.scheduled = true
+
+	time.AfterFunc(d.delay, func() {
+		d.running.Lock()
+		defer d.running.Unlock()
+
+		// Clear the mark first, so a trigger during the run schedules
+		// another one that sees its change.
+		d.mu.Lock()
+		d.scheduled = false
+		d.mu.Unlock()
+		d.run()
+	})
+}
@@ -0,0 +1,26 @@
+package rating
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncer(t *testing.T) {
+	var runs atomic.Int32
+	d := newDebouncer(20*time.Millisecond, func() { runs.Add(1) })
+
+	for range 5 {
+		d.trigger()
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("runs after a burst of triggers = %d, want 1", got)
+	}
+
+	d.trigger()
+	time.Sleep(100 * time.Millisecond)
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("runs after a later trigger = %d, want 2", got)
+	}
+}
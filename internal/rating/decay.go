@@ -0,0 +1,81 @@
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHalfLife = errors.New("invalid half-life")
+
+// minDecayedWeight is the weight below which a decayed matchup is dropped.
+// It is about 30 half-lives, and stops weights that have underflowed to zero
+// from leaving an item with no games to go on.
+const minDecayedWeight = 1e-9
+
+// Decay weights every matchup by its age at now, so one made halfLife before
+// now counts half as much as one made at now, and returns the matchups that
+// still count. Matchups made after now keep full weight. A non-positive
+// halfLife leaves the matchups alone.
+func Decay(matchups []Matchup, halfLife time.Duration, now time.Time) []Matchup {
+	if halfLife <= 0 {
+		return matchups
+	}
+	kept := matchups[:0]
+	for _, m := range matchups {
+		age := now.Sub(m.CreatedAt)
+		if age < 0 {
+			age = 0
+		}
+		m.Weight = math.Exp2(-age.Hours() / halfLife.Hours())
+		m.Weighted = true
+		if m.Weight < minDecayedWeight {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// ParseHalfLife reads a half-life given either in whole days, such as "90d",
+// or as a Go duration, such as "36h".
+func ParseHalfLife(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidHalfLife, s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidHalfLife, s)
+		}
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("%w: %q must be positive", ErrInvalidHalfLife, s)
+	}
+	return d, nil
+}
+
+// FormatHalfLife is the inverse of ParseHalfLife, preferring whole days.
+func FormatHalfLife(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	if d%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	}
+	return d.String()
+}
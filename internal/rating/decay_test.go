@@ -0,0 +1,43 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecay(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	matchups := []Matchup{
+		{ID: 1, CreatedAt: now},
+		{ID: 2, CreatedAt: now.Add(-time.Hour)},
+		{ID: 3, CreatedAt: now.Add(time.Hour)},
+		// Old enough for the weight to underflow to zero.
+		{ID: 4, CreatedAt: now.Add(-2000 * time.Hour)},
+	}
+
+	kept := Decay(matchups, time.Hour, now)
+
+	want := map[int]float64{1: 1, 2: 0.5, 3: 1}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d matchups, want %d: %+v", len(kept), len(want), kept)
+	}
+	for _, m := range kept {
+		w, ok := want[m.ID]
+		if !ok {
+			t.Errorf("matchup %d should have been dropped", m.ID)
+			continue
+		}
+		if math.Abs(m.weight()-w) > 1e-12 {
+			t.Errorf("matchup %d weight = %v, want %v", m.ID, m.weight(), w)
+		}
+	}
+}
+
+func TestDecayWithoutHalfLife(t *testing.T) {
+	matchups := []Matchup{{ID: 1, CreatedAt: time.Unix(0, 0)}}
+	kept := Decay(matchups, 0, time.Now())
+	if len(kept) != 1 || kept[0].weight() != 1 {
+		t.Errorf("Decay without a half-life = %+v, want the matchup at full weight", kept)
+	}
+}
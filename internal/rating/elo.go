@@ -0,0 +1,79 @@
+package rating
+
+import "math"
+
+type EloConfig struct {
+	KFactor       float64
+	InitialRating float64
+}
+
+func DefaultEloConfig() EloConfig {
+	return EloConfig{
+		KFactor:       32,
+		InitialRating: 1500,
+	}
+}
+
+// Elo updates ratings one matchup at a time, so the result depends on the
+// order matchups are processed in.
+type Elo struct {
+	cfg     EloConfig
+	ratings map[int]*Rating
+}
+
+func NewElo(cfg EloConfig) *Elo {
+	defaults := DefaultEloConfig()
+	if cfg.KFactor <= 0 {
+		cfg.KFactor = defaults.KFactor
+	}
+	if cfg.InitialRating == 0 {
+		cfg.InitialRating = defaults.InitialRating
+	}
+	return &Elo{cfg: cfg, ratings: make(map[int]*Rating)}
+}
+
+func (e *Elo) Process(matchups []Matchup) {
+	for _, m := range sortedCopy(matchups) {
+		e.apply(m)
+	}
+}
+
+func (e *Elo) apply(m Matchup) {
+	score, ok := m.Score()
+	if !ok || m.Item1ID == m.Item2ID {
+		return
+	}
+
+	a := e.get(m.Item1ID)
+	b := e.get(m.Item2ID)
+
+	expected := ExpectedScore(a.Rating, b.Rating)
+	delta := m.weight() * e.cfg.KFactor * (score - expected)
+
+	a.Rating += delta
+	b.Rating -= delta
+	a.Matches++
+	b.Matches++
+}
+
+func (e *Elo) get(itemID int) *Rating {
+	r, ok := e.ratings[itemID]
+	if !ok {
+		r = &Rating{ItemID: itemID, Rating: e.cfg.InitialRating}
+		e.ratings[itemID] = r
+	}
+	return r
+}
+
+func (e *Elo) Ratings() map[int]Rating {
+	ratings := make(map[int]Rating, len(e.ratings))
+	for id, r := range e.ratings {
+		ratings[id] = *r
+	}
+	return ratings
+}
+
+// ExpectedScore is the probability that a player rated a beats one rated b.
+func ExpectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
@@ -0,0 +1,69 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestElo(t *testing.T) {
+	start := time.Unix(0, 0)
+
+	tests := []struct {
+		name     string
+		kFactor  float64
+		matchups []Matchup
+		want     map[int]float64
+	}{
+		{
+			name:     "win between equals",
+			kFactor:  32,
+			matchups: []Matchup{{ID: 1, Item1ID: 1, Item2ID: 2, WinnerID: 1, CreatedAt: start}},
+			want:     map[int]float64{1: 1516, 2: 1484},
+		},
+		{
+			name:     "win with another K",
+			kFactor:  16,
+			matchups: []Matchup{{ID: 1, Item1ID: 1, Item2ID: 2, WinnerID: 2, CreatedAt: start}},
+			want:     map[int]float64{1: 1492, 2: 1508},
+		},
+		{
+			name:     "draw between equals",
+			kFactor:  32,
+			matchups: []Matchup{{ID: 1, Item1ID: 1, Item2ID: 2, Draw: true, CreatedAt: start}},
+			want:     map[int]float64{1: 1500, 2: 1500},
+		},
+		{
+			// After the first win 1 is at 1516 and 2 at 1484, so 1 is
+			// expected to score 1/(1+10^(-32/400)), about 0.54592.
+			name:    "draw after a win",
+			kFactor: 32,
+			matchups: []Matchup{
+				{ID: 1, Item1ID: 1, Item2ID: 2, WinnerID: 1, CreatedAt: start},
+				{ID: 2, Item1ID: 1, Item2ID: 2, Draw: true, CreatedAt: start.Add(time.Hour)},
+			},
+			want: map[int]float64{1: 1514.5305, 2: 1485.4695},
+		},
+		{
+			name:    "half-weight win",
+			kFactor: 32,
+			matchups: []Matchup{
+				{ID: 1, Item1ID: 1, Item2ID: 2, WinnerID: 1, CreatedAt: start, Weight: 0.5, Weighted: true},
+			},
+			want: map[int]float64{1: 1508, 2: 1492},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewElo(EloConfig{KFactor: tt.kFactor})
+			e.Process(tt.matchups)
+			ratings := e.Ratings()
+			for id, want := range tt.want {
+				if got := ratings[id].Rating; math.Abs(got-want) > 0.0001 {
+					t.Errorf("item %d = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package rating
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCachedViews bounds how many views the engine keeps ratings for.
+	maxCachedViews = 64
+	// decayRefresh is how long decayed ratings are reused before the
+	// matchups are weighed again, as they age even when nothing changes.
+	decayRefresh = time.Hour
+	// liveRefresh is how long the ratings table is reused before it is read
+	// again, so a recompute-ratings run shows up without a restart.
+	liveRefresh = time.Minute
+	// refreshDelay is how long the engine waits after a change before it
+	// recomputes the ratings table and refits personal ratings, so a run of
+	// votes costs a single recompute.
+	refreshDelay = 2 * time.Second
+)
+
+// View selects the matchups ratings are computed from and how they count.
+// The zero View rates every matchup at full weight.
+type View struct {
+	Segment Segment
+	// HalfLife, when set, decays each matchup's weight with its age. See
+	// Decay.
+	HalfLife time.Duration
+}
+
+func (v View) IsZero() bool {
+	return v.Segment.IsZero() && v.HalfLife <= 0
+}
+
+// String returns a canonical key for the view.
+func (v View) String() string {
+	if v.HalfLife <= 0 {
+		return v.Segment.String()
+	}
+	return v.Segment.String() + "@" + FormatHalfLife(v.HalfLife)
+}
+
+// Load reads the view's matchups, weighed by their age at now when the view
+// decays.
+func (v View) Load(ctx context.Context, q Querier, f Filter, now time.Time) ([]Matchup, error) {
+	f.Segment = v.Segment
+	matchups, err := LoadMatchups(ctx, q, f)
+	if err != nil {
+		return nil, err
+	}
+	return Decay(matchups, v.HalfLife, now), nil
+}
+
+// Engine serves ratings for the whole matchups table from the ratings
+// table, and keeps ratings for any other views that have been asked for in
+// memory. Invalidate schedules a background refresh that replays every
+// matchup into the ratings table. Until it has run, readers are served the
+// ratings from before the change rather than waiting for it.
+type Engine struct {
+	db *sql.DB
+	// method describes the rater newRater makes and its settings, as
+	// returned by RaterFor. It is stored alongside the ratings it produces.
+	method   string
+	newRater func() Rater
+	refresh  *debouncer
+
+	mu    sync.Mutex
+	views map[string]cachedRatings
+	// stale is set when a matchup has changed since the ratings table was
+	// last recomputed.
+	stale bool
+	// pending holds the users whose personal ratings the next refresh
+	// refits.
+	pending  map[int]PersonalConfig
+	onErrors []func(error)
+
+	historyMu sync.Mutex
+	// recorded holds the global ratings last written to the rating history.
+	recorded map[int]Rating
+}
+
+func NewEngine(db *sql.DB, method string, newRater func() Rater) *Engine {
+	e := &Engine{
+		db:       db,
+		method:   method,
+		newRater: newRater,
+		views:    make(map[string]cachedRatings),
+		pending:  make(map[int]PersonalConfig),
+	}
+	e.refresh = newDebouncer(refreshDelay, e.runRefresh)
+	return e
+}
+
+type cachedRatings struct {
+	ratings  map[int]Rating
+	computed time.Time
+}
+
+// OnError registers a function to be called with errors from background
+// refreshes, which have no caller to return them to.
+func (e *Engine) OnError(fn func(error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onErrors = append(e.onErrors, fn)
+}
+
+// Invalidate marks the ratings as out of date, typically after a matchup
+// has been recorded, revised or withdrawn, and schedules a refresh.
+func (e *Engine) Invalidate() {
+	e.mu.Lock()
+	e.stale = true
+	e.mu.Unlock()
+	e.refresh.trigger()
+}
+
+// PersonaliseLater refits the user's personal ratings in the next refresh,
+// after the global ratings they are fitted against have been recomputed.
+func (e *Engine) PersonaliseLater(userID int, cfg PersonalConfig) {
+	e.mu.Lock()
+	e.pending[userID] = cfg
+	e.mu.Unlock()
+	e.refresh.trigger()
+}
+
+func (e *Engine) Ratings(ctx context.Context) (map[int]Rating, error) {
+	return e.ViewRatings(ctx, View{})
+}
+
+// ViewRatings rates items using the matchups selected by v. The ratings are
+// worked out without holding the engine's lock, so a slow view does not hold
+// up readers of the others.
+func (e *Engine) ViewRatings(ctx context.Context, v View) (map[int]Rating, error) {
+	key := v.String()
+
+	e.mu.Lock()
+	cached, ok := e.views[key]
+	e.mu.Unlock()
+	switch {
+	case !ok:
+	case v.IsZero():
+		if time.Since(cached.computed) < liveRefresh {
+			return cached.ratings, nil
+		}
+	case v.HalfLife <= 0 || time.Since(cached.computed) < decayRefresh:
+		return cached.ratings, nil
+	}
+
+	now := time.Now()
+	var ratings map[int]Rating
+	if v.IsZero() {
+		var err error
+		if ratings, err = e.live(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		matchups, err := v.Load(ctx, e.db, Filter{}, now)
+		if err != nil {
+			return nil, err
+		}
+		r := e.newRater()
+		r.Process(matchups)
+		ratings = r.Ratings()
+	}
+
+	e.cache(key, ratings, now)
+	return ratings, nil
+}
+
+func (e *Engine) cache(key string, ratings map[int]Rating, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.views) >= maxCachedViews {
+		clear(e.views)
+	}
+	e.views[key] = cachedRatings{ratings: ratings, computed: now}
+}
+
+// live reads the ratings table. If it was filled by a different rater, a
+// refresh is scheduled to replace it and the table is served until then.
+func (e *Engine) live(ctx context.Context) (map[int]Rating, error) {
+	ratings, method, err := NewStore(e.db).Live(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if method != e.method {
+		e.Invalidate()
+		return ratings, nil
+	}
+	if err := e.recordHistory(ctx, ratings); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// runRefresh recomputes the ratings table if a matchup has changed, then
+// refits the personal ratings of the users waiting for it.
+func (e *Engine) runRefresh() {
+	ctx := context.Background()
+
+	e.mu.Lock()
+	stale := e.stale
+	e.stale = false
+	pending := e.pending
+	e.pending = make(map[int]PersonalConfig)
+	e.mu.Unlock()
+
+	if stale {
+		if err := e.recompute(ctx); err != nil {
+			e.mu.Lock()
+			e.stale = true
+			e.mu.Unlock()
+			e.report(err)
+		}
+	}
+	for userID, cfg := range pending {
+		if err := e.Personalise(ctx, userID, cfg); err != nil {
+			e.report(fmt.Errorf("personal ratings for user %d: %w", userID, err))
+		}
+	}
+}
+
+// recompute replays every matchup into the ratings table and swaps the
+// result in for readers. Other views are dropped, to be worked out again
+// from the current matchups when they are next asked for.
+func (e *Engine) recompute(ctx context.Context) error {
+	store := NewStore(e.db)
+	if _, err := store.Recompute(ctx, e.method, e.newRater()); err != nil {
+		return err
+	}
+	ratings, _, err := store.Live(ctx)
+	if err != nil {
+		return err
+	}
+	if err := e.recordHistory(ctx, ratings); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	clear(e.views)
+	e.views[View{}.String()] = cachedRatings{ratings: ratings, computed: time.Now()}
+	return nil
+}
+
+func (e *Engine) report(err error) {
+	e.mu.Lock()
+	onErrors := e.onErrors
+	e.mu.Unlock()
+	for _, fn := range onErrors {
+		fn(err)
+	}
+}
+
+// recordHistory writes the global ratings that changed since they were last
+// recorded to the rating history.
+func (e *Engine) recordHistory(ctx context.Context, ratings map[int]Rating) error {
+	e.historyMu.Lock()
+	defer e.historyMu.Unlock()
+
+	store := NewStore(e.db)
+	if e.recorded == nil {
+		latest, err := store.LatestHistory(ctx)
+		if err != nil {
+			return err
+		}
+		e.recorded = latest
+	}
+
+	var changed []Rating
+	for id, r := range ratings {
+		prev, ok := e.recorded[id]
+		if !ok || prev.Rating != r.Rating || prev.Deviation != r.Deviation || prev.Matches != r.Matches {
+			changed = append(changed, r)
+		}
+	}
+
+	if err := store.RecordHistory(ctx, changed); err != nil {
+		return err
+	}
+	e.recorded = ratings
+	return nil
+}
+
+// Personalise refits and stores the user's personal ratings against the
+// global ratings.
+func (e *Engine) Personalise(ctx context.Context, userID int, cfg PersonalConfig) error {
+	global, err := e.Ratings(ctx)
+	if err != nil {
+		return err
+	}
+	own, err := LoadMatchups(ctx, e.db, Filter{UserID: userID})
+	if err != nil {
+		return err
+	}
+	return NewStore(e.db).SavePersonal(ctx, userID, Personalise(global, own, cfg))
+}
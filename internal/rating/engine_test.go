@@ -0,0 +1,34 @@
+package rating
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInvalidateRefreshesOnce(t *testing.T) {
+	e := NewEngine(nil, RaterGlicko2, nil)
+	var runs atomic.Int32
+	var pending int
+	e.refresh = newDebouncer(20*time.Millisecond, func() {
+		runs.Add(1)
+		e.mu.Lock()
+		pending = len(e.pending)
+		e.mu.Unlock()
+	})
+
+	for range 5 {
+		e.Invalidate()
+		e.PersonaliseLater(7, DefaultPersonalConfig())
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.stale || pending != 1 {
+		t.Errorf("stale = %v with %d users waiting, want a stale engine with one user", e.stale, pending)
+	}
+}
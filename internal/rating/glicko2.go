@@ -0,0 +1,201 @@
+package rating
+
+import (
+	"math"
+	"time"
+)
+
+// glickoScale converts between the Glicko and Glicko-2 rating scales.
+const glickoScale = 173.7178
+
+type Glicko2Config struct {
+	InitialRating     float64
+	InitialDeviation  float64
+	InitialVolatility float64
+	// Tau constrains how quickly volatility can change. Sensible values are
+	// between 0.3 and 1.2.
+	Tau          float64
+	RatingPeriod time.Duration
+}
+
+func DefaultGlicko2Config() Glicko2Config {
+	return Glicko2Config{
+		InitialRating:     1500,
+		InitialDeviation:  350,
+		InitialVolatility: 0.06,
+		Tau:               0.5,
+		RatingPeriod:      7 * 24 * time.Hour,
+	}
+}
+
+// Glicko2 groups matchups into fixed-length rating periods and updates every
+// item at the end of each period. Deviation grows for items that go unrated,
+// so it doubles as a measure of how much the rating can be trusted.
+type Glicko2 struct {
+	cfg       Glicko2Config
+	ratings   map[int]Rating
+	pending   []Matchup
+	periodEnd time.Time
+}
+
+func NewGlicko2(cfg Glicko2Config) *Glicko2 {
+	defaults := DefaultGlicko2Config()
+	if cfg.InitialRating == 0 {
+		cfg.InitialRating = defaults.InitialRating
+	}
+	if cfg.InitialDeviation <= 0 {
+		cfg.InitialDeviation = defaults.InitialDeviation
+	}
+	if cfg.InitialVolatility <= 0 {
+		cfg.InitialVolatility = defaults.InitialVolatility
+	}
+	if cfg.Tau <= 0 {
+		cfg.Tau = defaults.Tau
+	}
+	if cfg.RatingPeriod <= 0 {
+		cfg.RatingPeriod = defaults.RatingPeriod
+	}
+	return &Glicko2{cfg: cfg, ratings: make(map[int]Rating)}
+}
+
+func (g *Glicko2) Process(matchups []Matchup) {
+	for _, m := range sortedCopy(matchups) {
+		if _, ok := m.Score(); !ok || m.Item1ID == m.Item2ID {
+			continue
+		}
+		if g.periodEnd.IsZero() {
+			g.periodEnd = m.CreatedAt.Add(g.cfg.RatingPeriod)
+		}
+		for !m.CreatedAt.Before(g.periodEnd) {
+			g.ratings = g.closePeriod()
+			g.pending = nil
+			g.periodEnd = g.periodEnd.Add(g.cfg.RatingPeriod)
+		}
+		g.pending = append(g.pending, m)
+	}
+}
+
+// Ratings includes the matchups from the current, unfinished rating period.
+func (g *Glicko2) Ratings() map[int]Rating {
+	return g.closePeriod()
+}
+
+func (g *Glicko2) closePeriod() map[int]Rating {
+	next := make(map[int]Rating, len(g.ratings))
+	for id, r := range g.ratings {
+		next[id] = r
+	}
+
+	games := make(map[int][]glickoGame)
+	for _, m := range g.pending {
+		score, _ := m.Score()
+		a := g.current(m.Item1ID)
+		b := g.current(m.Item2ID)
+		w := m.weight()
+		games[a.ItemID] = append(games[a.ItemID], glickoGame{opponent: b, score: score, weight: w})
+		games[b.ItemID] = append(games[b.ItemID], glickoGame{opponent: a, score: 1 - score, weight: w})
+		next[a.ItemID] = a
+		next[b.ItemID] = b
+	}
+
+	for id, r := range next {
+		next[id] = g.update(r, games[id])
+	}
+	return next
+}
+
+func (g *Glicko2) current(itemID int) Rating {
+	if r, ok := g.ratings[itemID]; ok {
+		return r
+	}
+	return Rating{
+		ItemID:     itemID,
+		Rating:     g.cfg.InitialRating,
+		Deviation:  g.cfg.InitialDeviation,
+		Volatility: g.cfg.InitialVolatility,
+	}
+}
+
+type glickoGame struct {
+	opponent Rating
+	score    float64
+	// weight scales the game's contribution to the variance and the
+	// improvement, so a half-weight game counts as half a game.
+	weight float64
+}
+
+// update applies step 2 to step 8 of the Glicko-2 algorithm to a single item.
+func (g *Glicko2) update(r Rating, games []glickoGame) Rating {
+	mu := (r.Rating - g.cfg.InitialRating) / glickoScale
+	phi := r.Deviation / glickoScale
+	sigma := r.Volatility
+
+	if len(games) == 0 {
+		phi = math.Min(math.Sqrt(phi*phi+sigma*sigma), g.cfg.InitialDeviation/glickoScale)
+		r.Deviation = phi * glickoScale
+		return r
+	}
+
+	var vInv, improvement float64
+	for _, game := range games {
+		muJ := (game.opponent.Rating - g.cfg.InitialRating) / glickoScale
+		gPhi := glickoG(game.opponent.Deviation / glickoScale)
+		e := 1 / (1 + math.Exp(-gPhi*(mu-muJ)))
+		vInv += game.weight * gPhi * gPhi * e * (1 - e)
+		improvement += game.weight * gPhi * (game.score - e)
+	}
+	v := 1 / vInv
+	delta := v * improvement
+
+	sigma = g.volatility(phi, sigma, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * improvement
+
+	r.Rating = mu*glickoScale + g.cfg.InitialRating
+	r.Deviation = phi * glickoScale
+	r.Volatility = sigma
+	r.Matches += len(games)
+	return r
+}
+
+// volatility finds the new volatility using the Illinois algorithm.
+func (g *Glicko2) volatility(phi, sigma, v, delta float64) float64 {
+	const epsilon = 0.000001
+	tau := g.cfg.Tau
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
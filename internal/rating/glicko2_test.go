@@ -0,0 +1,63 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestGlicko2Update checks the worked example from Glickman's "Example of
+// the Glicko-2 system".
+func TestGlicko2Update(t *testing.T) {
+	g := NewGlicko2(DefaultGlicko2Config())
+	player := Rating{ItemID: 1, Rating: 1500, Deviation: 200, Volatility: 0.06}
+	games := []glickoGame{
+		{opponent: Rating{ItemID: 2, Rating: 1400, Deviation: 30}, score: 1, weight: 1},
+		{opponent: Rating{ItemID: 3, Rating: 1550, Deviation: 100}, score: 0, weight: 1},
+		{opponent: Rating{ItemID: 4, Rating: 1700, Deviation: 300}, score: 0, weight: 1},
+	}
+
+	got := g.update(player, games)
+
+	tests := []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"rating", got.Rating, 1464.06, 0.01},
+		{"deviation", got.Deviation, 151.52, 0.01},
+		{"volatility", got.Volatility, 0.05999, 0.00001},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > tt.tolerance {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if got.Matches != 3 {
+		t.Errorf("matches = %d, want 3", got.Matches)
+	}
+}
+
+func TestGlicko2Draw(t *testing.T) {
+	g := NewGlicko2(DefaultGlicko2Config())
+	g.Process([]Matchup{{ID: 1, Item1ID: 1, Item2ID: 2, Draw: true, CreatedAt: time.Unix(0, 0)}})
+	ratings := g.Ratings()
+
+	a, b := ratings[1], ratings[2]
+	if a.Rating != 1500 || b.Rating != 1500 {
+		t.Errorf("ratings after a draw between equals = %v and %v, want 1500", a.Rating, b.Rating)
+	}
+	if a.Deviation >= 350 || a.Deviation != b.Deviation {
+		t.Errorf("deviations after a draw = %v and %v, want equal and below 350", a.Deviation, b.Deviation)
+	}
+}
+
+func TestGlicko2Unrated(t *testing.T) {
+	g := NewGlicko2(DefaultGlicko2Config())
+	r := g.update(Rating{ItemID: 1, Rating: 1600, Deviation: 50, Volatility: 0.06}, nil)
+
+	want := math.Sqrt(50*50/(glickoScale*glickoScale)+0.06*0.06) * glickoScale
+	if r.Rating != 1600 || math.Abs(r.Deviation-want) > 1e-9 {
+		t.Errorf("unrated item = %v/%v, want 1600/%v", r.Rating, r.Deviation, want)
+	}
+}
@@ -0,0 +1,89 @@
+package rating
+
+import "math"
+
+type PersonalConfig struct {
+	// Spread is the prior standard deviation, in rating points, of how far a
+	// user's opinion of an item strays from the global rating. Smaller values
+	// shrink personal ratings harder towards the global ones.
+	Spread     float64
+	Iterations int
+}
+
+func DefaultPersonalConfig() PersonalConfig {
+	return PersonalConfig{
+		Spread:     150,
+		Iterations: 25,
+	}
+}
+
+// Personalise blends a user's own matchups with the global ratings. Each item
+// the user compared gets an offset from its global rating, fitted to the
+// user's results under a normal prior centred on zero, so a handful of votes
+// nudges the rating and many votes can move it a long way. Items the user has
+// not compared keep their global rating and are not returned.
+func Personalise(global map[int]Rating, own []Matchup, cfg PersonalConfig) map[int]Rating {
+	defaults := DefaultPersonalConfig()
+	if cfg.Spread <= 0 {
+		cfg.Spread = defaults.Spread
+	}
+	if cfg.Iterations <= 0 {
+		cfg.Iterations = defaults.Iterations
+	}
+
+	// c converts rating differences into log-odds.
+	c := math.Ln10 / 400
+	priorPrecision := 1 / (cfg.Spread * cfg.Spread)
+
+	base := func(itemID int) float64 {
+		if r, ok := global[itemID]; ok {
+			return r.Rating
+		}
+		return DefaultEloConfig().InitialRating
+	}
+
+	var games []Matchup
+	offsets := make(map[int]float64)
+	matches := make(map[int]int)
+	for _, m := range own {
+		if _, ok := m.Score(); !ok || m.Item1ID == m.Item2ID {
+			continue
+		}
+		games = append(games, m)
+		offsets[m.Item1ID] = 0
+		offsets[m.Item2ID] = 0
+		matches[m.Item1ID]++
+		matches[m.Item2ID]++
+	}
+
+	information := make(map[int]float64)
+	for iter := 0; iter < cfg.Iterations; iter++ {
+		gradient := make(map[int]float64, len(offsets))
+		clear(information)
+		for _, m := range games {
+			score, _ := m.Score()
+			diff := base(m.Item1ID) + offsets[m.Item1ID] - base(m.Item2ID) - offsets[m.Item2ID]
+			p := 1 / (1 + math.Exp(-c*diff))
+			gradient[m.Item1ID] += c * (score - p)
+			gradient[m.Item2ID] -= c * (score - p)
+			information[m.Item1ID] += c * c * p * (1 - p)
+			information[m.Item2ID] += c * c * p * (1 - p)
+		}
+		// One diagonal Newton step per iteration on the log posterior.
+		for id, offset := range offsets {
+			g := gradient[id] - offset*priorPrecision
+			offsets[id] = offset + g/(information[id]+priorPrecision)
+		}
+	}
+
+	personal := make(map[int]Rating, len(offsets))
+	for id, offset := range offsets {
+		personal[id] = Rating{
+			ItemID:    id,
+			Rating:    base(id) + offset,
+			Deviation: 1 / math.Sqrt(information[id]+priorPrecision),
+			Matches:   matches[id],
+		}
+	}
+	return personal
+}
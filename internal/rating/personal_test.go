@@ -0,0 +1,61 @@
+package rating
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPersonalise(t *testing.T) {
+	global := map[int]Rating{
+		1: {ItemID: 1, Rating: 1400, Deviation: 60},
+		2: {ItemID: 2, Rating: 1600, Deviation: 60},
+		3: {ItemID: 3, Rating: 1500, Deviation: 60},
+		4: {ItemID: 4, Rating: 1500, Deviation: 60},
+	}
+	start := time.Unix(0, 0)
+	matchup := func(id, item1, item2, winner int) Matchup {
+		m := Matchup{ID: id, Item1ID: item1, Item2ID: item2, WinnerID: winner, CreatedAt: start.Add(time.Duration(id) * time.Hour)}
+		m.Draw = winner == 0
+		return m
+	}
+
+	t.Run("no matchups", func(t *testing.T) {
+		// Items the user has not compared are left to their global ratings.
+		if personal := Personalise(global, nil, DefaultPersonalConfig()); len(personal) != 0 {
+			t.Errorf("Personalise with no matchups = %v, want no personal ratings", personal)
+		}
+	})
+
+	t.Run("one strong preference", func(t *testing.T) {
+		var own []Matchup
+		for i := 1; i <= 6; i++ {
+			own = append(own, matchup(i, 1, 2, 1))
+		}
+		// A draw between two items rated the same says they are as good as
+		// the global ratings suggest.
+		own = append(own, matchup(7, 3, 4, 0))
+
+		personal := Personalise(global, own, DefaultPersonalConfig())
+
+		if personal[1].Rating <= personal[2].Rating {
+			t.Errorf("preferred item = %v, other = %v, want the preferred item above", personal[1].Rating, personal[2].Rating)
+		}
+		if personal[1].Rating <= global[1].Rating || personal[2].Rating >= global[2].Rating {
+			t.Errorf("ratings = %v and %v, want them moved towards the user's preference", personal[1].Rating, personal[2].Rating)
+		}
+		// The prior keeps a handful of votes from moving an item much
+		// further than its spread.
+		if shift := personal[1].Rating - global[1].Rating; shift > 2*DefaultPersonalConfig().Spread {
+			t.Errorf("preferred item moved %v, want the prior to hold it back", shift)
+		}
+		for _, id := range []int{3, 4} {
+			if math.Abs(personal[id].Rating-global[id].Rating) > 1e-9 {
+				t.Errorf("item %d = %v, want its global rating %v", id, personal[id].Rating, global[id].Rating)
+			}
+			if personal[id].Matches != 1 {
+				t.Errorf("item %d matches = %d, want 1", id, personal[id].Matches)
+			}
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// Package rating turns pairwise matchups into per-item ratings.
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var ErrUnknownRater = errors.New("unknown rater")
+
+// Matchup is a single comparison between two items made by a user.
+type Matchup struct {
+	ID       int
+	Item1ID  int
+	Item2ID  int
+	WinnerID int
+	// Draw is set when the user could not split the two items. WinnerID is
+	// zero for a draw.
+	Draw      bool
+	UserID    int
+	CreatedAt time.Time
+	// Weight scales how much the matchup counts, for example after Decay.
+	// It is only used when Weighted is set, so a zero Matchup counts in
+	// full.
+	Weight   float64
+	Weighted bool
+}
+
+// weight returns how much the matchup counts, defaulting to 1.
+func (m Matchup) weight() float64 {
+	if !m.Weighted {
+		return 1
+	}
+	return m.Weight
+}
+
+// Score returns the result for item1: 1 for a win, 0.5 for a draw and 0 for
+// a loss. ok is false when the winner is neither item in the matchup.
+func (m Matchup) Score() (score float64, ok bool) {
+	if m.Draw {
+		return 0.5, true
+	}
+	switch m.WinnerID {
+	case m.Item1ID:
+		return 1, true
+	case m.Item2ID:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+type Rating struct {
+	ItemID     int     `json:"item_id"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility,omitempty"`
+	Matches    int     `json:"matches"`
+}
+
+// ProvisionalDeviation is the deviation above which a rating should be shown
+// as provisional rather than ranked alongside established items.
+const ProvisionalDeviation = 110
+
+func (r Rating) Provisional() bool {
+	return r.Deviation > ProvisionalDeviation
+}
+
+// Rater maintains a rating for every item it has seen.
+type Rater interface {
+	// Process consumes matchups in created_at order.
+	Process(matchups []Matchup)
+	Ratings() map[int]Rating
+}
+
+// Raters are the names RaterFor accepts.
+const (
+	RaterGlicko2      = "glicko2"
+	RaterElo          = "elo"
+	RaterBradleyTerry = "bradley-terry"
+)
+
+// RaterFor returns a constructor for the named rater, with kFactor used by
+// elo. An empty name is glicko2 and a kFactor of zero is the default. method
+// describes the rater and its settings, such as "elo:k=16", and is what
+// ratings made by it are stored under, so ratings from other settings can be
+// told apart.
+func RaterFor(name string, kFactor float64) (method string, newRater func() Rater, err error) {
+	switch name {
+	case "", RaterGlicko2:
+		return RaterGlicko2, func() Rater { return NewGlicko2(DefaultGlicko2Config()) }, nil
+	case RaterElo:
+		cfg := DefaultEloConfig()
+		if kFactor > 0 {
+			cfg.KFactor = kFactor
+		}
+		method := fmt.Sprintf("%s:k=%g", RaterElo, cfg.KFactor)
+		return method, func() Rater { return NewElo(cfg) }, nil
+	case RaterBradleyTerry:
+		return RaterBradleyTerry, func() Rater { return NewBradleyTerry(DefaultBradleyTerryConfig()) }, nil
+	default:
+		return "", nil, fmt.Errorf("%w %q", ErrUnknownRater, name)
+	}
+}
+
+// SortMatchups orders matchups by created_at, using the id to break ties.
+func SortMatchups(matchups []Matchup) {
+	sort.SliceStable(matchups, func(i, j int) bool {
+		if matchups[i].CreatedAt.Equal(matchups[j].CreatedAt) {
+			return matchups[i].ID < matchups[j].ID
+		}
+		return matchups[i].CreatedAt.Before(matchups[j].CreatedAt)
+	})
+}
+
+// Ranked returns ratings from highest to lowest.
+func Ranked(ratings map[int]Rating) []Rating {
+	ranked := make([]Rating, 0, len(ratings))
+	for _, rt := range ratings {
+		ranked = append(ranked, rt)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Rating == ranked[j].Rating {
+			return ranked[i].ItemID < ranked[j].ItemID
+		}
+		return ranked[i].Rating > ranked[j].Rating
+	})
+	return ranked
+}
+
+func sortedCopy(matchups []Matchup) []Matchup {
+	sorted := make([]Matchup, len(matchups))
+	copy(sorted, matchups)
+	SortMatchups(sorted)
+	return sorted
+}
@@ -0,0 +1,38 @@
+package rating
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRaterFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		kFactor float64
+		method  string
+	}{
+		{name: "", method: "glicko2"},
+		{name: "glicko2", kFactor: 16, method: "glicko2"},
+		{name: "elo", method: "elo:k=32"},
+		{name: "elo", kFactor: 16, method: "elo:k=16"},
+		{name: "elo", kFactor: 12.5, method: "elo:k=12.5"},
+		{name: "bradley-terry", method: "bradley-terry"},
+	}
+
+	for _, tt := range tests {
+		method, newRater, err := RaterFor(tt.name, tt.kFactor)
+		if err != nil {
+			t.Fatalf("RaterFor(%q, %v): %v", tt.name, tt.kFactor, err)
+		}
+		if method != tt.method {
+			t.Errorf("RaterFor(%q, %v) method = %q, want %q", tt.name, tt.kFactor, method, tt.method)
+		}
+		if newRater() == nil {
+			t.Errorf("RaterFor(%q, %v) made a nil rater", tt.name, tt.kFactor)
+		}
+	}
+
+	if _, _, err := RaterFor("trueskill", 0); !errors.Is(err, ErrUnknownRater) {
+		t.Errorf("RaterFor(trueskill) error = %v, want ErrUnknownRater", err)
+	}
+}
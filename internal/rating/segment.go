@@ -0,0 +1,97 @@
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidSegment = errors.New("invalid segment expression")
+
+// Segment restricts ratings to matchups made by users whose profile matches
+// every condition. The zero Segment matches everyone.
+type Segment struct {
+	// Preferences are matched against user_profiles.preferences. A key
+	// matches when its value equals the string or is an array containing it.
+	Preferences map[string]string
+	// Diet lists dietary restrictions the user must have.
+	Diet []string
+}
+
+// ParseSegment reads a comma separated list of key:value conditions, for
+// example "nationality:italian,diet:vegetarian". The key "diet" matches
+// dietary restrictions and every other key matches a preference.
+func ParseSegment(expr string) (Segment, error) {
+	var seg Segment
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return seg, nil
+	}
+
+	for _, cond := range strings.Split(expr, ",") {
+		key, value, ok := strings.Cut(cond, ":")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return Segment{}, fmt.Errorf("%w: %q", ErrInvalidSegment, cond)
+		}
+
+		if key == "diet" {
+			seg.Diet = append(seg.Diet, value)
+			continue
+		}
+		if seg.Preferences == nil {
+			seg.Preferences = make(map[string]string)
+		}
+		if _, dup := seg.Preferences[key]; dup {
+			return Segment{}, fmt.Errorf("%w: %q is repeated", ErrInvalidSegment, key)
+		}
+		seg.Preferences[key] = value
+	}
+	return seg, nil
+}
+
+func (s Segment) IsZero() bool {
+	return len(s.Preferences) == 0 && len(s.Diet) == 0
+}
+
+// String returns the segment in canonical form, so equal segments give equal
+// strings and it can be used as a cache key.
+func (s Segment) String() string {
+	var conds []string
+	for key, value := range s.Preferences {
+		conds = append(conds, key+":"+value)
+	}
+	for _, diet := range s.Diet {
+		conds = append(conds, "diet:"+diet)
+	}
+	sort.Strings(conds)
+	return strings.Join(conds, ",")
+}
+
+// where returns the conditions on a user_profiles row aliased p, numbering
+// placeholders after the args already in use.
+func (s Segment) where(args []any) (string, []any) {
+	var conds []string
+
+	keys := make([]string, 0, len(s.Preferences))
+	for key := range s.Preferences {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		args = append(args, key, s.Preferences[key])
+		k, v := len(args)-1, len(args)
+		conds = append(conds, fmt.Sprintf(
+			"(p.preferences->>$%d::text = $%d::text OR p.preferences->$%d::text ? $%d::text)", k, v, k, v,
+		))
+	}
+	for _, diet := range s.Diet {
+		args = append(args, diet)
+		conds = append(conds, fmt.Sprintf("$%d::text = ANY(p.dietary_restrictions)", len(args)))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
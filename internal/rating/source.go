@@ -0,0 +1,100 @@
+package rating
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Querier is satisfied by *sql.DB and *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Filter restricts which matchups are loaded. Zero values match everything.
+type Filter struct {
+	From    time.Time
+	To      time.Time
+	UserID  int
+	Segment Segment
+}
+
+func (f Filter) where() (string, []any) {
+	conditions := []string{"outcome <> 'skip'"}
+	var args []any
+
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if f.UserID != 0 {
+		args = append(args, f.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if !f.Segment.IsZero() {
+		var profile string
+		profile, args = f.Segment.where(args)
+		conditions = append(conditions, "user_id IN (SELECT p.user_id FROM user_profiles p WHERE "+profile+")")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// LoadMatchups reads every matchup that passes f in created_at order. Skipped
+// matchups carry no information about either item and are left out.
+func LoadMatchups(ctx context.Context, q Querier, f Filter) ([]Matchup, error) {
+	where, args := f.where()
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT id, item1_id, item2_id, COALESCE(winner_id, 0), outcome = 'draw',
+			COALESCE(user_id, 0), created_at
+		FROM matchups
+		WHERE `+where+`
+		ORDER BY created_at, id`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matchups []Matchup
+	for rows.Next() {
+		var m Matchup
+		err := rows.Scan(
+			&m.ID,
+			&m.Item1ID,
+			&m.Item2ID,
+			&m.WinnerID,
+			&m.Draw,
+			&m.UserID,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		matchups = append(matchups, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matchups, nil
+}
+
+// Replay loads every matchup that passes f and feeds it to r.
+func Replay(ctx context.Context, q Querier, r Rater, f Filter) error {
+	matchups, err := LoadMatchups(ctx, q, f)
+	if err != nil {
+		return err
+	}
+	r.Process(matchups)
+	return nil
+}
@@ -0,0 +1,548 @@
+package rating
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoHistory        = errors.New("no rating history")
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+type Snapshot struct {
+	ID           int       `json:"id"`
+	Method       string    `json:"method"`
+	WindowStart  time.Time `json:"window_start,omitempty"`
+	WindowEnd    time.Time `json:"window_end,omitempty"`
+	UserID       int       `json:"user_id,omitempty"`
+	MatchupCount int       `json:"matchup_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	Ratings      []Rating  `json:"ratings"`
+}
+
+// HistoryPoint is an item's rating as it stood at RecordedAt.
+type HistoryPoint struct {
+	ItemID     int       `json:"item_id"`
+	Rating     float64   `json:"rating"`
+	Deviation  float64   `json:"deviation"`
+	Matches    int       `json:"matches"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// SaveSnapshot writes a snapshot header and all of its ratings in one
+// transaction.
+func (s *Store) SaveSnapshot(ctx context.Context, snap *Snapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO rating_snapshots (method, window_start, window_end, user_id, matchup_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		RETURNING id, created_at`,
+		snap.Method,
+		nullTime(snap.WindowStart),
+		nullTime(snap.WindowEnd),
+		nullInt(snap.UserID),
+		snap.MatchupCount,
+	).Scan(&snap.ID, &snap.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO rating_snapshot_items (snapshot_id, item_id, rating, std_error, matches)
+		VALUES ($1, $2, $3, $4, $5)`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range snap.Ratings {
+		_, err := stmt.ExecContext(ctx, snap.ID, r.ItemID, r.Rating, r.Deviation, r.Matches)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Snapshots returns up to limit snapshots, newest first, without their
+// ratings.
+func (s *Store) Snapshots(ctx context.Context, limit int) ([]Snapshot, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+snapshotColumns+`
+		FROM rating_snapshots
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snap)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// Snapshot returns the snapshot with its ratings, best first.
+func (s *Store) Snapshot(ctx context.Context, id int) (*Snapshot, error) {
+	snap, err := scanSnapshot(s.db.QueryRowContext(
+		ctx,
+		`SELECT `+snapshotColumns+`
+		FROM rating_snapshots
+		WHERE id = $1`,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item_id, rating, std_error, matches
+		FROM rating_snapshot_items
+		WHERE snapshot_id = $1
+		ORDER BY rating DESC, item_id`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snap.Ratings = []Rating{}
+	for rows.Next() {
+		var r Rating
+		err := rows.Scan(
+			&r.ItemID,
+			&r.Rating,
+			&r.Deviation,
+			&r.Matches,
+		)
+		if err != nil {
+			return nil, err
+		}
+		snap.Ratings = append(snap.Ratings, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snap, nil
+}
+
+const snapshotColumns = `id, method, window_start, window_end, COALESCE(user_id, 0), matchup_count, created_at`
+
+func scanSnapshot(row interface{ Scan(...any) error }) (*Snapshot, error) {
+	var snap Snapshot
+	var start, end sql.NullTime
+	err := row.Scan(
+		&snap.ID,
+		&snap.Method,
+		&start,
+		&end,
+		&snap.UserID,
+		&snap.MatchupCount,
+		&snap.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	snap.WindowStart = start.Time
+	snap.WindowEnd = end.Time
+	return &snap, nil
+}
+
+// SavePersonal replaces every personal rating the user has.
+func (s *Store) SavePersonal(ctx context.Context, userID int, ratings map[int]Rating) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM personal_ratings WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO personal_ratings (user_id, item_id, rating, deviation, own_matches, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range ratings {
+		_, err := stmt.ExecContext(ctx, userID, r.ItemID, r.Rating, r.Deviation, r.Matches)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PersonalTop returns the user's highest personal ratings.
+func (s *Store) PersonalTop(ctx context.Context, userID, limit int) ([]Rating, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item_id, rating, deviation, own_matches
+		FROM personal_ratings
+		WHERE user_id = $1
+		ORDER BY rating DESC, item_id
+		LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ratings []Rating
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.ItemID, &r.Rating, &r.Deviation, &r.Matches); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+// Personal returns every personal rating the user has.
+func (s *Store) Personal(ctx context.Context, userID int) (map[int]Rating, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item_id, rating, deviation, own_matches
+		FROM personal_ratings
+		WHERE user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make(map[int]Rating)
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.ItemID, &r.Rating, &r.Deviation, &r.Matches); err != nil {
+			return nil, err
+		}
+		ratings[r.ItemID] = r
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+// RecordHistory appends the ratings to the rating history.
+func (s *Store) RecordHistory(ctx context.Context, ratings []Rating) error {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO rating_history (item_id, rating, deviation, matches, recorded_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range ratings {
+		_, err := stmt.ExecContext(ctx, r.ItemID, r.Rating, r.Deviation, r.Matches)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LatestHistory returns the most recent history entry for every item.
+func (s *Store) LatestHistory(ctx context.Context) (map[int]Rating, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT DISTINCT ON (item_id) item_id, rating, deviation, matches
+		FROM rating_history
+		ORDER BY item_id, recorded_at DESC, id DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[int]Rating)
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.ItemID, &r.Rating, &r.Deviation, &r.Matches); err != nil {
+			return nil, err
+		}
+		latest[r.ItemID] = r
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return latest, nil
+}
+
+// RatingAt returns the last rating recorded for the item at or before t.
+func (s *Store) RatingAt(ctx context.Context, itemID int, t time.Time) (*HistoryPoint, error) {
+	var p HistoryPoint
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT item_id, rating, deviation, matches, recorded_at
+		FROM rating_history
+		WHERE item_id = $1 AND recorded_at <= $2
+		ORDER BY recorded_at DESC, id DESC
+		LIMIT 1`,
+		itemID, t,
+	).Scan(
+		&p.ItemID,
+		&p.Rating,
+		&p.Deviation,
+		&p.Matches,
+		&p.RecordedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNoHistory
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// History returns every rating recorded for the item in [from, to), oldest
+// first. A zero from or to leaves that end open.
+func (s *Store) History(ctx context.Context, itemID int, from, to time.Time) ([]HistoryPoint, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item_id, rating, deviation, matches, recorded_at
+		FROM rating_history
+		WHERE item_id = $1
+			AND ($2::timestamp IS NULL OR recorded_at >= $2)
+			AND ($3::timestamp IS NULL OR recorded_at < $3)
+		ORDER BY recorded_at, id`,
+		itemID, nullTime(from), nullTime(to),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []HistoryPoint
+	for rows.Next() {
+		var p HistoryPoint
+		err := rows.Scan(
+			&p.ItemID,
+			&p.Rating,
+			&p.Deviation,
+			&p.Matches,
+			&p.RecordedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// Recompute replays every matchup through r and replaces the live ratings
+// table with the result, labelled with method. The matchups are read and the
+// table rewritten in one transaction, so readers see either the old ratings
+// or the new ones and never a mix. It returns the number of matchups
+// replayed.
+func (s *Store) Recompute(ctx context.Context, method string, r Rater) (int, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Block a second recompute, and any other writer, until this one is
+	// committed while still letting readers through.
+	_, err = tx.ExecContext(ctx, `LOCK TABLE ratings IN SHARE ROW EXCLUSIVE MODE`)
+	if err != nil {
+		return 0, err
+	}
+
+	matchups, err := LoadMatchups(ctx, tx, Filter{})
+	if err != nil {
+		return 0, err
+	}
+	r.Process(matchups)
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM ratings`)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO ratings (item_id, method, rating, deviation, volatility, matches, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, rt := range r.Ratings() {
+		_, err := stmt.ExecContext(ctx, rt.ItemID, method, rt.Rating, rt.Deviation, rt.Volatility, rt.Matches)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(matchups), nil
+}
+
+// Live returns the ratings table and the rater it was computed with. The
+// method is empty when the table has never been filled.
+func (s *Store) Live(ctx context.Context) (map[int]Rating, string, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT item_id, method, rating, deviation, volatility, matches
+		FROM ratings`,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	ratings := make(map[int]Rating)
+	var method string
+	for rows.Next() {
+		var r Rating
+		err := rows.Scan(
+			&r.ItemID,
+			&method,
+			&r.Rating,
+			&r.Deviation,
+			&r.Volatility,
+			&r.Matches,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		ratings[r.ItemID] = r
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return ratings, method, nil
+}
+
+// RunBradleyTerry refits Bradley-Terry strengths over every matchup that
+// passes f and stores them as a new snapshot.
+func RunBradleyTerry(ctx context.Context, db *sql.DB, f Filter, cfg BradleyTerryConfig) (*Snapshot, error) {
+	matchups, err := LoadMatchups(ctx, db, f)
+	if err != nil {
+		return nil, err
+	}
+
+	bt := NewBradleyTerry(cfg)
+	bt.Process(matchups)
+
+	snap := &Snapshot{
+		Method:       "bradley-terry",
+		WindowStart:  f.From,
+		WindowEnd:    f.To,
+		UserID:       f.UserID,
+		MatchupCount: len(matchups),
+		Ratings:      Ranked(bt.Ratings()),
+	}
+	if err := NewStore(db).SaveSnapshot(ctx, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
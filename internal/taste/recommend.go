@@ -0,0 +1,131 @@
+package taste
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+type Recommendation struct {
+	ItemID int    `json:"item_id"`
+	Name   string `json:"name"`
+	// Score is the predicted preference, from -0.5 for an item the user
+	// would always pass over up to 0.5 for one they would always pick.
+	Score      float64 `json:"score"`
+	Neighbours int     `json:"neighbours"`
+	Reason     string  `json:"reason"`
+}
+
+type neighbour struct {
+	userID     int
+	similarity float64
+	share      float64
+	shared     int
+}
+
+// Recommend predicts how much the user would like every item they have not
+// compared yet, from the preferences of the users who agree with them most
+// on the pairs they share. Only users with positive similarity are
+// consulted. Items are returned best first, up to limit.
+func Recommend(userID int, matchups []rating.Matchup, cfg Config, limit int) []Recommendation {
+	defaults := DefaultConfig()
+	if cfg.Neighbours <= 0 {
+		cfg.Neighbours = defaults.Neighbours
+	}
+	if cfg.Shrinkage <= 0 {
+		cfg.Shrinkage = defaults.Shrinkage
+	}
+	if cfg.MinSupport <= 0 {
+		cfg.MinSupport = defaults.MinSupport
+	}
+
+	users := histories(matchups)
+	own := users[userID]
+	if len(own) == 0 {
+		return nil
+	}
+	compared := own.preferences()
+
+	var neighbours []neighbour
+	for id, h := range users {
+		if id == userID {
+			continue
+		}
+		share, shared := agreement(own, h)
+		if sim := similarity(share, shared, cfg.Shrinkage); sim > 0 {
+			neighbours = append(neighbours, neighbour{id, sim, share, shared})
+		}
+	}
+	sort.Slice(neighbours, func(i, j int) bool {
+		if neighbours[i].similarity == neighbours[j].similarity {
+			return neighbours[i].userID < neighbours[j].userID
+		}
+		return neighbours[i].similarity > neighbours[j].similarity
+	})
+	if len(neighbours) > cfg.Neighbours {
+		neighbours = neighbours[:cfg.Neighbours]
+	}
+
+	type prediction struct {
+		weighted, weights float64
+		support           int
+		// closest is the most similar neighbour who picked the item more
+		// often than not.
+		closest *neighbour
+	}
+	predictions := make(map[int]*prediction)
+	for i, n := range neighbours {
+		for itemID, pref := range users[n.userID].preferences() {
+			if _, seen := compared[itemID]; seen {
+				continue
+			}
+			p := predictions[itemID]
+			if p == nil {
+				p = &prediction{}
+				predictions[itemID] = p
+			}
+			p.weighted += n.similarity * pref
+			p.weights += n.similarity
+			p.support++
+			if pref > 0 && p.closest == nil {
+				p.closest = &neighbours[i]
+			}
+		}
+	}
+
+	var recs []Recommendation
+	for itemID, p := range predictions {
+		score := p.weighted / p.weights
+		if p.support < cfg.MinSupport || score <= 0 || p.closest == nil {
+			continue
+		}
+		recs = append(recs, Recommendation{
+			ItemID:     itemID,
+			Score:      score,
+			Neighbours: p.support,
+			Reason:     reason(p.support, *p.closest),
+		})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score == recs[j].Score {
+			return recs[i].ItemID < recs[j].ItemID
+		}
+		return recs[i].Score > recs[j].Score
+	})
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	return recs
+}
+
+func reason(support int, closest neighbour) string {
+	people := "person"
+	if support != 1 {
+		people = "people"
+	}
+	return fmt.Sprintf(
+		"Compared by %d %s with similar taste. One who picked it agrees with you on %.0f%% of %d shared comparisons.",
+		support, people, closest.share*100, closest.shared,
+	)
+}
@@ -0,0 +1,186 @@
+package taste
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/lib/pq"
+)
+
+const defaultLimit = 10
+
+type RatingSource interface {
+	Ratings(ctx context.Context) (map[int]rating.Rating, error)
+}
+
+type Service struct {
+	db      *sql.DB
+	ratings RatingSource
+	cfg     Config
+}
+
+func NewService(db *sql.DB, ratings RatingSource, cfg Config) *Service {
+	return &Service{db: db, ratings: ratings, cfg: cfg}
+}
+
+// Recommendations returns up to limit items the user has not compared that
+// users with similar taste rate highly.
+func (s *Service) Recommendations(ctx context.Context, userID, limit int) ([]Recommendation, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	matchups, err := rating.LoadMatchups(ctx, s.db, rating.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	recs := Recommend(userID, matchups, s.cfg, limit)
+	ids := make([]int64, len(recs))
+	for i, r := range recs {
+		ids[i] = int64(r.ItemID)
+	}
+	names, err := s.names(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recs {
+		recs[i].Name = names[recs[i].ItemID]
+	}
+	return recs, nil
+}
+
+// Similarity compares two users' matchups and personal ratings.
+func (s *Service) Similarity(ctx context.Context, userID, otherID int) (*Similarity, error) {
+	var owners [2]history
+	var personal [2]map[int]rating.Rating
+	store := rating.NewStore(s.db)
+	for i, id := range []int{userID, otherID} {
+		matchups, err := rating.LoadMatchups(ctx, s.db, rating.Filter{UserID: id})
+		if err != nil {
+			return nil, err
+		}
+		owners[i] = histories(matchups)[id]
+		personal[i], err = store.Personal(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	global, err := s.ratings.Ratings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sim, err := compare(owners[0], owners[1], personal[0], personal[1], global, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for _, places := range [][]Place{sim.Agree, sim.Disagree} {
+		for _, p := range places {
+			ids = append(ids, int64(p.ItemID))
+		}
+	}
+	names, err := s.names(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, places := range [][]Place{sim.Agree, sim.Disagree} {
+		for i := range places {
+			places[i].Name = names[places[i].ItemID]
+		}
+	}
+	return sim, nil
+}
+
+// Blend returns up to limit places both users are predicted to enjoy, best
+// first. Each user's prediction is their personal rating where they have
+// one and the global rating otherwise, and places are ranked by the lower
+// of the two so neither user is left with somewhere they would not like.
+func (s *Service) Blend(ctx context.Context, userID, otherID, limit int) ([]Place, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	global, err := s.ratings.Ratings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	store := rating.NewStore(s.db)
+	own, err := store.Personal(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	other, err := store.Personal(ctx, otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	predict := func(personal map[int]rating.Rating, id int) float64 {
+		if r, ok := personal[id]; ok {
+			return r.Rating
+		}
+		return global[id].Rating
+	}
+
+	places := make([]Place, 0, len(global))
+	for id := range global {
+		places = append(places, Place{
+			ItemID:      id,
+			Rating:      predict(own, id),
+			OtherRating: predict(other, id),
+		})
+	}
+	sort.Slice(places, func(i, j int) bool {
+		a, b := places[i].joint(), places[j].joint()
+		if a == b {
+			return places[i].ItemID < places[j].ItemID
+		}
+		return a > b
+	})
+	if len(places) > limit {
+		places = places[:limit]
+	}
+
+	ids := make([]int64, len(places))
+	for i, p := range places {
+		ids[i] = int64(p.ItemID)
+	}
+	names, err := s.names(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range places {
+		places[i].Name = names[places[i].ItemID]
+	}
+	return places, nil
+}
+
+func (s *Service) names(ctx context.Context, ids []int64) (map[int]string, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, name FROM items WHERE id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
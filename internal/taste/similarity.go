@@ -0,0 +1,143 @@
+package taste
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+var ErrNoOverlap = errors.New("users have nothing in common to compare")
+
+// placesShown is how many places are listed as agreed and disagreed on.
+const placesShown = 5
+
+// minCorrelated is the fewest items both users must have rated before their
+// ratings are correlated.
+const minCorrelated = 3
+
+// Place is an item both users have compared, with each user's personal
+// rating of it.
+type Place struct {
+	ItemID      int     `json:"item_id"`
+	Name        string  `json:"name"`
+	Rating      float64 `json:"rating"`
+	OtherRating float64 `json:"other_rating"`
+}
+
+func (p Place) gap() float64 {
+	return math.Abs(p.Rating - p.OtherRating)
+}
+
+// joint is how much the pair would enjoy the place together, which is no
+// more than the less keen of the two.
+func (p Place) joint() float64 {
+	return math.Min(p.Rating, p.OtherRating)
+}
+
+type Similarity struct {
+	// Agreement is from 0, for users who always disagree, to 100 for users
+	// who always agree.
+	Agreement   float64 `json:"agreement"`
+	SharedPairs int     `json:"shared_pairs"`
+	SharedItems int     `json:"shared_items"`
+	// Method says what Agreement was worked out from: "pairs", "ratings"
+	// or "pairs+ratings" when there were too few shared pairs to go on.
+	Method   string  `json:"method"`
+	Agree    []Place `json:"agree"`
+	Disagree []Place `json:"disagree"`
+}
+
+// compare scores how closely two users agree. Shared pairs are the most
+// direct evidence, so once there are cfg.MinSharedPairs of them the score is
+// the share of those results that match. With fewer, it is blended with the
+// correlation of how far each user's personal ratings stray from the global
+// ones, which only needs the users to have rated some of the same items.
+func compare(own, other history, ownRatings, otherRatings, global map[int]rating.Rating, cfg Config) (*Similarity, error) {
+	if cfg.MinSharedPairs <= 0 {
+		cfg.MinSharedPairs = DefaultConfig().MinSharedPairs
+	}
+
+	share, shared := agreement(own, other)
+	sim := &Similarity{SharedPairs: shared, Agree: []Place{}, Disagree: []Place{}}
+
+	var places []Place
+	var ownOffsets, otherOffsets []float64
+	for id, r := range ownRatings {
+		o, ok := otherRatings[id]
+		if !ok {
+			continue
+		}
+		places = append(places, Place{ItemID: id, Rating: r.Rating, OtherRating: o.Rating})
+		base := r.Rating
+		if g, ok := global[id]; ok {
+			base = g.Rating
+		}
+		ownOffsets = append(ownOffsets, r.Rating-base)
+		otherOffsets = append(otherOffsets, o.Rating-base)
+	}
+	sim.SharedItems = len(places)
+
+	corr, correlated := correlation(ownOffsets, otherOffsets)
+	switch {
+	case shared >= cfg.MinSharedPairs || (shared > 0 && !correlated):
+		sim.Method = "pairs"
+		sim.Agreement = share
+	case correlated && shared == 0:
+		sim.Method = "ratings"
+		sim.Agreement = (corr + 1) / 2
+	case correlated:
+		sim.Method = "pairs+ratings"
+		w := float64(shared) / float64(cfg.MinSharedPairs)
+		sim.Agreement = w*share + (1-w)*(corr+1)/2
+	default:
+		return nil, ErrNoOverlap
+	}
+	sim.Agreement *= 100
+
+	sort.Slice(places, func(i, j int) bool {
+		if places[i].gap() == places[j].gap() {
+			return places[i].ItemID < places[j].ItemID
+		}
+		return places[i].gap() < places[j].gap()
+	})
+	// With only a few places in common, split them rather than listing the
+	// same place as both agreed and disagreed on.
+	disagree := min(placesShown, len(places)/2)
+	agree := min(placesShown, len(places)-disagree)
+	sim.Agree = append(sim.Agree, places[:agree]...)
+	for i := len(places) - 1; i >= len(places)-disagree; i-- {
+		sim.Disagree = append(sim.Disagree, places[i])
+	}
+	return sim, nil
+}
+
+// correlation returns the Pearson correlation of xs and ys. ok is false when
+// there are too few values or either set has no spread.
+func correlation(xs, ys []float64) (r float64, ok bool) {
+	n := float64(len(xs))
+	if len(xs) < minCorrelated {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
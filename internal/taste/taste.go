@@ -0,0 +1,109 @@
+// Package taste compares users by the matchups they have made, to find
+// people who agree with each other and what they would recommend.
+package taste
+
+import (
+	"math"
+
+	"github.com/Jerell/tasteranker/internal/rating"
+)
+
+type Config struct {
+	// Neighbours is how many of the most similar users are consulted.
+	Neighbours int
+	// Shrinkage is the number of shared pairs at which a neighbour's
+	// similarity counts for half of its raw agreement.
+	Shrinkage float64
+	// MinSupport is how many neighbours must have compared an item before
+	// it is recommended.
+	MinSupport int
+	// MinSharedPairs is how many pairs two users must share before their
+	// similarity is judged on those pairs alone. Below it, the correlation
+	// of their personal ratings makes up the difference.
+	MinSharedPairs int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Neighbours:     20,
+		Shrinkage:      5,
+		MinSupport:     2,
+		MinSharedPairs: 5,
+	}
+}
+
+// history is one user's results, keyed by the pair of items with the lower
+// id first. The value is the score of the lower id item.
+type history map[[2]int]float64
+
+func histories(matchups []rating.Matchup) map[int]history {
+	users := make(map[int]history)
+	for _, m := range matchups {
+		score, ok := m.Score()
+		if !ok || m.Item1ID == m.Item2ID || m.UserID == 0 {
+			continue
+		}
+		key := [2]int{m.Item1ID, m.Item2ID}
+		if key[0] > key[1] {
+			key = [2]int{key[1], key[0]}
+			score = 1 - score
+		}
+		if users[m.UserID] == nil {
+			users[m.UserID] = make(history)
+		}
+		users[m.UserID][key] = score
+	}
+	return users
+}
+
+// preferences returns, for every item in the history, the share of the
+// score it took less a half. Positive values are items the user tends to
+// pick.
+func (h history) preferences() map[int]float64 {
+	scores := make(map[int]float64)
+	games := make(map[int]float64)
+	for pair, score := range h {
+		scores[pair[0]] += score
+		scores[pair[1]] += 1 - score
+		games[pair[0]]++
+		games[pair[1]]++
+	}
+	prefs := make(map[int]float64, len(scores))
+	for id, s := range scores {
+		prefs[id] = s/games[id] - 0.5
+	}
+	return prefs
+}
+
+// agreement compares two histories over the pairs both have judged. It
+// returns the share of agreement, from 0 when every result was reversed to 1
+// when every result matched, and the number of shared pairs.
+func agreement(a, b history) (share float64, shared int) {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var agree float64
+	for pair, sa := range a {
+		sb, ok := b[pair]
+		if !ok {
+			continue
+		}
+		agree += 1 - math.Abs(sa-sb)
+		shared++
+	}
+	if shared == 0 {
+		return 0, 0
+	}
+	return agree / float64(shared), shared
+}
+
+// similarity maps agreement onto -1 to 1 and shrinks it towards zero when
+// only a few pairs are shared, so two users who happened to agree once do
+// not look like taste twins.
+func similarity(share float64, shared int, shrinkage float64) float64 {
+	if shared == 0 {
+		return 0
+	}
+	n := float64(shared)
+	return (2*share - 1) * n / (n + shrinkage)
+}
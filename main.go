@@ -2,16 +2,34 @@ package main
 
 import (
 	"context"
+	"log"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/Jerell/tasteranker/api/admin"
+	"github.com/Jerell/tasteranker/api/blends"
+	chainroutes "github.com/Jerell/tasteranker/api/chains"
+	"github.com/Jerell/tasteranker/api/comparisons"
+	"github.com/Jerell/tasteranker/api/groups"
 	"github.com/Jerell/tasteranker/api/htmlcontent"
+	"github.com/Jerell/tasteranker/api/items"
+	"github.com/Jerell/tasteranker/api/leaderboards"
+	"github.com/Jerell/tasteranker/api/restaurants"
 	"github.com/Jerell/tasteranker/api/users"
 	"github.com/Jerell/tasteranker/components"
+	"github.com/Jerell/tasteranker/handlers"
+	"github.com/Jerell/tasteranker/internal/analysis"
+	"github.com/Jerell/tasteranker/internal/blend"
+	"github.com/Jerell/tasteranker/internal/chains"
 	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/leaderboard"
+	"github.com/Jerell/tasteranker/internal/pairing"
+	"github.com/Jerell/tasteranker/internal/planning"
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/Jerell/tasteranker/internal/taste"
 	"github.com/Jerell/tasteranker/tigris"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -25,6 +43,19 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "recompute-ratings" {
+		if err := recomputeRatings(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		if err := snapshotRatings(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	e := echo.New()
 
 	err := godotenv.Load()
@@ -74,6 +105,7 @@ func main() {
 			echo.HeaderAuthorization,
 			"HX-Current-URL",
 			"HX-Request",
+			"X-CSRF-Token",
 			"Access-Control-Request-Headers",
 			"Access-Control-Request-Method",
 		},
@@ -91,7 +123,7 @@ func main() {
 	}
 
 	e.Use(middleware.CSRFWithConfig(middleware.CSRFConfig{
-		TokenLookup: "form:_csrf",
+		TokenLookup: "form:_csrf,header:X-CSRF-Token",
 		CookieName:  "csrf_token",
 		CookiePath:  "/",
 		Skipper:     csrfSkipper,
@@ -149,16 +181,75 @@ func main() {
 		)
 	})
 
-	e.GET("/", func(c echo.Context) error {
-		return components.Render(
-			c, http.StatusOK,
-			components.Main(components.Home()),
-		)
+	userStore := db.NewUserStore(database)
+	matchupStore := db.NewMatchupStore(database)
+
+	raterMethod, newRater, err := rating.RaterFor(configuredRater())
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	ratingEngine := rating.NewEngine(database, raterMethod, newRater)
+	ratingEngine.OnError(func(err error) {
+		e.Logger.Errorf("refreshing ratings: %v", err)
+	})
+	chainService := chains.NewService(database, ratingEngine, newRater)
+	matchupStore.OnChange(func(ctx context.Context, change db.MatchupChange, m db.Matchup) {
+		ratingEngine.Invalidate()
+		chainService.Invalidate()
+		ratingEngine.PersonaliseLater(m.UserID, rating.DefaultPersonalConfig())
 	})
 
+	pairService := pairing.NewService(pairing.DefaultConfig(), matchupStore, ratingEngine)
+	comparisonHandler := handlers.NewComparisonHandler(pairService, matchupStore, userStore)
+
+	leaderboardService := leaderboard.NewService(database, ratingEngine, newRater)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService, rating.NewStore(database))
+
+	homeHandler := handlers.NewHomeHandler(comparisonHandler, leaderboardService, userStore)
+	e.GET("/", homeHandler.Home)
+
+	comparisonsGroup := e.Group("/comparisons")
+	comparisons.UseSubroute(comparisonsGroup, comparisonHandler)
+
+	leaderboardGroup := e.Group("/leaderboard")
+	leaderboards.UseSubroute(leaderboardGroup, leaderboardHandler)
+
+	chainHandler := handlers.NewChainHandler(chainService)
+	chainsGroup := e.Group("/chains")
+	chainroutes.UseSubroute(chainsGroup, chainHandler)
+
+	adminHandler := handlers.NewAdminHandler(analysis.NewService(database), rating.NewStore(database))
+	adminGroup := e.Group("/admin", auth.RequireAdmin)
+	admin.UseSubroute(adminGroup, adminHandler)
+
+	tasteService := taste.NewService(database, ratingEngine, taste.DefaultConfig())
+	groupStore := db.NewGroupStore(database)
+	tasteHandler := handlers.NewTasteHandler(tasteService, userStore, groupStore)
+
+	blendKey := os.Getenv("BLEND_SECRET")
+	if blendKey == "" {
+		e.Logger.Fatal("BLEND_SECRET environment variable is required")
+	}
+	blendService := blend.NewService(database, blend.NewSigner([]byte(blendKey)), blend.DefaultTTL)
+	blendHandler := handlers.NewBlendHandler(blendService, tasteService, userStore)
+	blendGroup := e.Group("/blend")
+	blends.UseSubroute(blendGroup, blendHandler)
+
+	planner := planning.NewService(database, groupStore, ratingEngine)
+	groupHandler := handlers.NewGroupHandler(groupStore, planner, userStore)
+	groupsGroup := e.Group("/groups")
+	groups.UseSubroute(groupsGroup, groupHandler)
+
+	itemHandler := handlers.NewItemHandler(db.NewItemStore(database), userStore)
+	itemsGroup := e.Group("/items")
+	items.UseSubroute(itemsGroup, itemHandler)
+
+	restaurantHandler := handlers.NewRestaurantHandler(db.NewRestaurantStore(database))
+	restaurantsGroup := e.Group("/restaurants")
+	restaurants.UseSubroute(restaurantsGroup, restaurantHandler)
+
 	usersGroup := e.Group("/users/")
-	userStore := db.NewUserStore(database)
-	users.UseSubroute(usersGroup, userStore)
+	users.UseSubroute(usersGroup, userStore, tasteHandler)
 
 	htmlGroup := e.Group("/html/")
 	htmlcontent.UseSubroute(htmlGroup)
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/joho/godotenv"
+)
+
+// configuredRater reads the RATER and ELO_K environment variables, which
+// pick the rater the server keeps the ratings table in.
+func configuredRater() (string, float64) {
+	name := os.Getenv("RATER")
+	if name == "" {
+		name = rating.RaterGlicko2
+	}
+	kFactor, _ := strconv.ParseFloat(os.Getenv("ELO_K"), 64)
+	return name, kFactor
+}
+
+// recomputeRatings implements the recompute-ratings subcommand. It replays
+// the whole matchups table through the server's rater, set by RATER and
+// ELO_K, and swaps the result into the ratings table the server reads, for
+// use after cleaning up matchups.
+func recomputeRatings(args []string) error {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Error loading .env file in development")
+	}
+
+	flags := flag.NewFlagSet("recompute-ratings", flag.ExitOnError)
+	flags.Parse(args)
+
+	method, newRater, err := rating.RaterFor(configuredRater())
+	if err != nil {
+		return err
+	}
+
+	database, err := db.NewConnection(db.NewConfig())
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	n, err := rating.NewStore(database).Recompute(context.Background(), method, newRater())
+	if err != nil {
+		return err
+	}
+	log.Printf("Replayed %d matchups through %s", n, method)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Jerell/tasteranker/internal/db"
+	"github.com/Jerell/tasteranker/internal/rating"
+	"github.com/joho/godotenv"
+)
+
+// snapshotRatings implements the snapshot subcommand. It fits Bradley-Terry
+// strengths over the matchups in a time window, optionally one user's, and
+// stores them as a rating snapshot that admins can read back.
+func snapshotRatings(args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	from := flags.String("from", "", "only matchups made on or after this date or RFC 3339 time")
+	to := flags.String("to", "", "only matchups made before this date or RFC 3339 time")
+	userID := flags.Int("user", 0, "only this user's matchups")
+	flags.Parse(args)
+
+	var f rating.Filter
+	var err error
+	if f.From, err = parseFlagTime(*from); err != nil {
+		return fmt.Errorf("-from: %w", err)
+	}
+	if f.To, err = parseFlagTime(*to); err != nil {
+		return fmt.Errorf("-to: %w", err)
+	}
+	f.UserID = *userID
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Error loading .env file in development")
+	}
+
+	database, err := db.NewConnection(db.NewConfig())
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	snap, err := rating.RunBradleyTerry(context.Background(), database, f, rating.DefaultBradleyTerryConfig())
+	if err != nil {
+		return err
+	}
+	log.Printf("Saved snapshot %d rating %d items from %d matchups", snap.ID, len(snap.Ratings), snap.MatchupCount)
+	return nil
+}
+
+func parseFlagTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}